@@ -0,0 +1,290 @@
+package goburnbooks
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// AuditReport lists every book whose ledger entries do not reconcile. A book
+// that failed to burn and ended up in a dead letter pile is accounted for, so
+// it is not reported as taken but unburned.
+type AuditReport struct {
+	// The number of times each book that was burned more than once was burned.
+	BurnedTwice map[string]int
+
+	// Books that were burned without ever being taken from a pile.
+	BurnedUntaken []string
+
+	// The pile that dropped each book instead of supplying it, e.g. a staging
+	// pile that was drained while a batch was on its way.
+	Dropped map[string]string
+
+	// The pile of each book that was declared as a supply but never left it.
+	NeverTaken map[string]string
+
+	// The number of times each book that was taken more than once was taken.
+	TakenTwice map[string]int
+
+	// Books that were taken but neither burned nor failed.
+	TakenUnburned []string
+}
+
+// Clean returns true if the report has no violation.
+func (ar AuditReport) Clean() bool {
+	return len(ar.Violations()) == 0
+}
+
+// Violations describes every violation in the report, ordered by kind and id.
+func (ar AuditReport) Violations() []string {
+	violations := make([]string, 0)
+
+	for _, id := range sortedIDs(ar.BurnedTwice) {
+		violations = append(violations, fmt.Sprintf(
+			"%s was burned %d times",
+			id,
+			ar.BurnedTwice[id],
+		))
+	}
+
+	for _, id := range ar.BurnedUntaken {
+		violations = append(violations, fmt.Sprintf("%s was burned without being taken", id))
+	}
+
+	for _, id := range sortedPileIDs(ar.Dropped) {
+		violations = append(violations, fmt.Sprintf(
+			"%s was dropped by pile %s",
+			id,
+			ar.Dropped[id],
+		))
+	}
+
+	for _, id := range sortedPileIDs(ar.NeverTaken) {
+		violations = append(violations, fmt.Sprintf(
+			"%s never left pile %s",
+			id,
+			ar.NeverTaken[id],
+		))
+	}
+
+	for _, id := range sortedIDs(ar.TakenTwice) {
+		violations = append(violations, fmt.Sprintf(
+			"%s was taken %d times",
+			id,
+			ar.TakenTwice[id],
+		))
+	}
+
+	for _, id := range ar.TakenUnburned {
+		violations = append(violations, fmt.Sprintf("%s was taken but never burned", id))
+	}
+
+	return violations
+}
+
+func sortedIDs(counts map[string]int) []string {
+	ids := make([]string, 0)
+
+	for id := range counts {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+func sortedPileIDs(piles map[string]string) []string {
+	ids := make([]string, 0)
+
+	for id := range piles {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+// Auditor reconciles what left the supply piles with what was burned, so that
+// every book is accounted for exactly once.
+type Auditor interface {
+	// Declare the supplies that a pile started out with, so that those that never
+	// leave it can be found.
+	RecordSupply(pileID string, supplyIDs ...string)
+	RecordTaken(result SupplyTakeResult)
+	RecordBurned(result BurnResult)
+
+	// Record the supplies that a pile dropped, which are lost rather than never
+	// taken. Recording the same supply again has no effect.
+	RecordDropped(pileID string, supplyIDs ...string)
+
+	// Get the violations that are already certain while the run is ongoing, i.e.
+	// books that were taken or burned more than once, or dropped.
+	Live() AuditReport
+
+	// Reconcile everything that has been recorded, assuming the run is over.
+	Reconcile() AuditReport
+}
+
+// AuditorParams represents all the required parameters to build an Auditor.
+// Violations are logged the moment they become certain.
+type AuditorParams struct {
+	Logger Logger
+}
+
+type auditor struct {
+	AuditorParams
+	log      LeveledLogger
+	mutex    sync.Mutex
+	burned   map[string]int
+	dropped  map[string]string
+	failed   map[string]int
+	supplied map[string]string
+	taken    map[string]int
+}
+
+func (a *auditor) RecordSupply(pileID string, supplyIDs ...string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, id := range supplyIDs {
+		a.supplied[id] = pileID
+	}
+}
+
+func (a *auditor) RecordDropped(pileID string, supplyIDs ...string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, id := range supplyIDs {
+		if _, ok := a.dropped[id]; !ok {
+			a.log.Error("book dropped", Field(FieldBookID, id), Field(FieldPeerID, pileID))
+		}
+
+		a.dropped[id] = pileID
+	}
+}
+
+func (a *auditor) RecordTaken(result SupplyTakeResult) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, id := range result.SupplyIDs() {
+		a.taken[id]++
+
+		if count := a.taken[id]; count > 1 {
+			a.log.Error(
+				"book taken more than once",
+				Field(FieldBookID, id),
+				Field("count", count),
+				Field(FieldPeerID, result.PileID()),
+			)
+		}
+	}
+}
+
+func (a *auditor) RecordBurned(result BurnResult) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	id := result.Burned().BurnableID()
+
+	if result.Err() != nil {
+		a.failed[id]++
+		return
+	}
+
+	a.burned[id]++
+
+	if count := a.burned[id]; count > 1 {
+		a.log.Error(
+			"book burned more than once",
+			Field(FieldBookID, id),
+			Field("count", count),
+			Field(FieldPeerID, result.IncineratorID()),
+		)
+	}
+}
+
+func (a *auditor) Live() AuditReport {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return AuditReport{
+		BurnedTwice: a.repeated(a.burned),
+		Dropped:     a.droppedPiles(),
+		TakenTwice:  a.repeated(a.taken),
+	}
+}
+
+func (a *auditor) Reconcile() AuditReport {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	report := AuditReport{
+		BurnedTwice: a.repeated(a.burned),
+		Dropped:     a.droppedPiles(),
+		NeverTaken:  make(map[string]string, 0),
+		TakenTwice:  a.repeated(a.taken),
+	}
+
+	for id := range a.burned {
+		if a.taken[id] == 0 {
+			report.BurnedUntaken = append(report.BurnedUntaken, id)
+		}
+	}
+
+	for id, pileID := range a.supplied {
+		if _, dropped := a.dropped[id]; !dropped && a.taken[id] == 0 {
+			report.NeverTaken[id] = pileID
+		}
+	}
+
+	for id := range a.taken {
+		if a.burned[id] == 0 && a.failed[id] == 0 {
+			report.TakenUnburned = append(report.TakenUnburned, id)
+		}
+	}
+
+	sort.Strings(report.BurnedUntaken)
+	sort.Strings(report.TakenUnburned)
+	return report
+}
+
+// Only call this while holding the mutex.
+func (a *auditor) droppedPiles() map[string]string {
+	dropped := make(map[string]string, len(a.dropped))
+
+	for id, pileID := range a.dropped {
+		dropped[id] = pileID
+	}
+
+	return dropped
+}
+
+// Only call this while holding the mutex.
+func (a *auditor) repeated(counts map[string]int) map[string]int {
+	repeated := make(map[string]int, 0)
+
+	for id, count := range counts {
+		if count > 1 {
+			repeated[id] = count
+		}
+	}
+
+	return repeated
+}
+
+// NewAuditor creates a new Auditor. It does not log anything if no logger is
+// specified.
+func NewAuditor(params *AuditorParams) Auditor {
+	a := &auditor{
+		AuditorParams: *params,
+		burned:        make(map[string]int, 0),
+		dropped:       make(map[string]string, 0),
+		failed:        make(map[string]int, 0),
+		supplied:      make(map[string]string, 0),
+		taken:         make(map[string]int, 0),
+	}
+
+	a.log = Leveled(a.Logger).With(Field(FieldActor, "auditor"))
+	return a
+}
@@ -0,0 +1,115 @@
+package goburnbooks
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestBurnResult(id string, err error) BurnResult {
+	return NewBurnResult(&BurnResultParams{
+		Attempts:      1,
+		Burned:        NewBook(&BookParams{ID: id}),
+		Err:           err,
+		IncineratorID: "0",
+		ProviderID:    "0",
+	})
+}
+
+func Test_AuditingMismatchedLedgers_ShouldReportEveryViolation(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	auditor := NewAuditor(&AuditorParams{})
+	auditor.RecordSupply("0", "ok", "twice", "lost", "failed", "stuck", "dropped")
+
+	/// When
+	auditor.RecordTaken(NewTakeResult("0", "0", []string{"ok", "twice", "lost"}))
+	auditor.RecordTaken(NewTakeResult("0", "1", []string{"failed", "twice"}))
+	auditor.RecordBurned(newTestBurnResult("ok", nil))
+	auditor.RecordBurned(newTestBurnResult("twice", nil))
+	auditor.RecordBurned(newTestBurnResult("twice", nil))
+	auditor.RecordBurned(newTestBurnResult("failed", ErrBurnFailed))
+	auditor.RecordBurned(newTestBurnResult("stray", nil))
+	auditor.RecordDropped("staging", "dropped")
+	live := auditor.Live()
+	report := auditor.Reconcile()
+
+	/// Then
+	if !reflect.DeepEqual(live.BurnedTwice, map[string]int{"twice": 2}) {
+		t.Errorf("Should have found double burns live, got %v", live.BurnedTwice)
+	}
+
+	if !reflect.DeepEqual(live.Dropped, map[string]string{"dropped": "staging"}) {
+		t.Errorf("Should have found dropped books live, got %v", live.Dropped)
+	}
+
+	if len(live.TakenUnburned) > 0 || len(live.NeverTaken) > 0 {
+		t.Errorf("Should not have reconciled while live")
+	}
+
+	expected := []string{
+		"twice was burned 2 times",
+		"stray was burned without being taken",
+		"dropped was dropped by pile staging",
+		"stuck never left pile 0",
+		"twice was taken 2 times",
+		"lost was taken but never burned",
+	}
+
+	if violations := report.Violations(); !reflect.DeepEqual(violations, expected) {
+		t.Errorf("Should have reported %v, got %v", expected, violations)
+	}
+
+	if report.Clean() {
+		t.Errorf("Should not be clean")
+	}
+}
+
+func Test_SimulatingWithAuditor_ShouldReconcileAllBooks(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	clock := NewFakeClock(time.Unix(0, 0))
+	suite := NewDefaultTestSuite()
+	suite.clock = clock
+	players := suite.SetUpSystem()
+	defer players.Terminate()
+
+	/// When
+	report, err := players.WaitAdvancing(clock, suite.waitDuration)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Completed || len(report.Violations) > 0 {
+		t.Errorf("Should have completed without violations, got %v", report.Violations)
+	}
+
+	audit := players.simulation.Auditor().Reconcile()
+
+	if !audit.Clean() {
+		t.Errorf("Should have been clean, got %v", audit.Violations())
+	}
+}
+
+func Test_CancellingAuditedSimulation_ShouldReportUntakenBooks(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	suite.gopherCount = 0
+	players := suite.SetUpSystem()
+
+	/// When
+	players.Terminate()
+	report, err := players.Wait(suite.waitDuration)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Violations) != int(suite.TotalSupplyCount()) {
+		t.Errorf("Should have found %d books stuck in their piles, got %d", suite.TotalSupplyCount(), len(report.Violations))
+	}
+}
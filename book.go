@@ -1,24 +1,53 @@
 package goburnbooks
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 )
 
+// ErrBurnFailed is returned when a Book fails to burn.
+var ErrBurnFailed = errors.New("book failed to burn")
+
 // BookParams represents the required parameters to set up a Book.
 type BookParams struct {
 	BurnDuration time.Duration
+	Clock        Clock
 	ID           string
+
+	// The number of times burning this book fails before it succeeds.
+	FailCount uint
+
+	// This describes the book, and decides which incinerators accept it.
+	Metadata Metadata
+
+	// Books of higher priority are supplied and burned first. Among books of
+	// equal priority, those with the earliest deadline go first, and those
+	// without a deadline last.
+	Deadline time.Time
+	Priority int
+
+	// How much capacity this book takes up, where 0 means the same as 1.
+	Weight uint
 }
 
 // Book represents a Book.
 type Book interface {
-	Burnable
+	Deadlined
+	Described
+	FallibleBurnable
+	Prioritized
+	TimedBurnable
 	Suppliable
+	Weighted
 }
 
 type book struct {
 	BookParams
+	attempts uint32
+	batchID  atomic.Value
 }
 
 func (b *book) String() string {
@@ -33,13 +62,78 @@ func (b *book) SuppliableID() string {
 	return b.ID
 }
 
+func (b *book) supplyBatchID() string {
+	batchID, _ := b.batchID.Load().(string)
+	return batchID
+}
+
+func (b *book) stampSupplyBatch(batchID string) {
+	b.batchID.Store(batchID)
+}
+
+func (b *book) Deadline() time.Time {
+	return b.BookParams.Deadline
+}
+
+func (b *book) Metadata() Metadata {
+	return b.BookParams.Metadata
+}
+
+func (b *book) Priority() int {
+	return b.BookParams.Priority
+}
+
+func (b *book) Weight() uint {
+	if b.BookParams.Weight == 0 {
+		return 1
+	}
+
+	return b.BookParams.Weight
+}
+
+func (b *book) ExpectedBurnDuration() time.Duration {
+	return b.BurnDuration
+}
+
 func (b *book) Burn() {
-	time.Sleep(b.BurnDuration)
+	b.Clock.Sleep(b.BurnDuration)
 }
 
-// NewBook returns a Burnable and Suppliable book.
+func (b *book) TryBurn(ctx context.Context) error {
+	attempts := atomic.AddUint32(&b.attempts, 1)
+
+	select {
+	case <-b.Clock.After(b.BurnDuration):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if uint(attempts) <= b.FailCount {
+		return ErrBurnFailed
+	}
+
+	return nil
+}
+
+// NewBook returns a Burnable and Suppliable book. It burns on the real clock if
+// none is specified.
 func NewBook(params *BookParams) Book {
-	return &book{BookParams: *params}
+	b := &book{BookParams: *params}
+	b.Clock = clockOrDefault(b.Clock)
+	return b
+}
+
+// ExtractSuppliablesFromBurnables extract Suppliables from a number of Burnables.
+func ExtractSuppliablesFromBurnables(burnables ...Burnable) []Suppliable {
+	suppliables := make([]Suppliable, 0)
+
+	for _, burnable := range burnables {
+		if book, ok := burnable.(Suppliable); ok {
+			suppliables = append(suppliables, book)
+		}
+	}
+
+	return suppliables
 }
 
 // ExtractBurnablesFromSuppliables extract Burnables from a number of Suppliables.
@@ -1,21 +1,101 @@
 package goburnbooks
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
-// BurnResult represents the result of a burning.
+// BurnResult represents the result of a burning. A result with an error means
+// the Burnable could not be burned even after retrying. If it could not be
+// buried in the dead letter pile either, e.g. because the pile is full, the
+// bury error says why, and the Burnable is lost.
+//
+// The batch is the one the incinerator received the Burnable in, with batch IDs
+// such as "incinerator-1", and the sequence numbers every Burnable the
+// incinerator received in order, starting from 1. The start
+// time is when the Burnable was received, and the end time is when it was done
+// burning, including all retries. Queue wait is how long the Burnable waited
+// for a free burning slot in between.
+//
+// A Burnable that an idle sibling stole from the incinerator it was provided to
+// is burned by the sibling, and records the incinerator it was stolen from.
+//
+// The metadata is that of the Burnable, if it is Described. The deadline is
+// that of the Burnable, if it has one. A burn meets it if it
+// succeeded no later than the deadline, and burns without one always do.
 type BurnResult interface {
+	Attempts() uint
 	Burned() Burnable
+	BuryErr() error
+	Err() error
 	IncineratorID() string
 	ProviderID() string
+	BatchID() string
+	Sequence() uint64
+	StartTime() time.Time
+	EndTime() time.Time
+	QueueWait() time.Duration
+	Deadline() time.Time
+	MetDeadline() bool
+	Metadata() Metadata
+	StolenFrom() string
+}
+
+// BurnResultParams represents the required parameters to build a BurnResult.
+type BurnResultParams struct {
+	Attempts      uint
+	BatchID       string
+	Burned        Burnable
+	BuryErr       error
+	EndTime       time.Time
+	Err           error
+	IncineratorID string
+	ProviderID    string
+	QueueWait     time.Duration
+	Sequence      uint64
+	StartTime     time.Time
+	StolenFrom    string
 }
 
 type burnResult struct {
+	attempts      uint
+	batchID       string
 	burned        Burnable
+	buryErr       error
+	endTime       time.Time
+	err           error
 	incineratorID string
 	providerID    string
+	queueWait     time.Duration
+	sequence      uint64
+	startTime     time.Time
+	stolenFrom    string
 }
 
 func (br *burnResult) String() string {
+	if br.buryErr != nil {
+		return fmt.Sprintf(
+			"Failed to burn %v with incinerator %s after %d attempts, provided by %s: %v, and failed to bury it: %v",
+			br.burned,
+			br.incineratorID,
+			br.attempts,
+			br.providerID,
+			br.err,
+			br.buryErr,
+		)
+	}
+
+	if br.err != nil {
+		return fmt.Sprintf(
+			"Failed to burn %v with incinerator %s after %d attempts, provided by %s: %v",
+			br.burned,
+			br.incineratorID,
+			br.attempts,
+			br.providerID,
+			br.err,
+		)
+	}
+
 	return fmt.Sprintf(
 		"Burned %v with incinerator %s, provided by %s",
 		br.burned,
@@ -24,10 +104,22 @@ func (br *burnResult) String() string {
 	)
 }
 
+func (br *burnResult) Attempts() uint {
+	return br.attempts
+}
+
 func (br *burnResult) Burned() Burnable {
 	return br.burned
 }
 
+func (br *burnResult) BuryErr() error {
+	return br.buryErr
+}
+
+func (br *burnResult) Err() error {
+	return br.err
+}
+
 func (br *burnResult) IncineratorID() string {
 	return br.incineratorID
 }
@@ -36,7 +128,102 @@ func (br *burnResult) ProviderID() string {
 	return br.providerID
 }
 
+func (br *burnResult) BatchID() string {
+	return br.batchID
+}
+
+func (br *burnResult) Sequence() uint64 {
+	return br.sequence
+}
+
+func (br *burnResult) StartTime() time.Time {
+	return br.startTime
+}
+
+func (br *burnResult) EndTime() time.Time {
+	return br.endTime
+}
+
+func (br *burnResult) QueueWait() time.Duration {
+	return br.queueWait
+}
+
+func (br *burnResult) Deadline() time.Time {
+	return deadlineOf(br.burned)
+}
+
+func (br *burnResult) StolenFrom() string {
+	return br.stolenFrom
+}
+
+func (br *burnResult) Metadata() Metadata {
+	return metadataOf(br.burned)
+}
+
+func (br *burnResult) MetDeadline() bool {
+	deadline := br.Deadline()
+	return deadline.IsZero() || (br.err == nil && !br.endTime.After(deadline))
+}
+
 // NewBurnResult returns a new BurnResult.
-func NewBurnResult(burned Burnable, incID string, provID string) BurnResult {
-	return &burnResult{burned: burned, incineratorID: incID, providerID: provID}
+func NewBurnResult(params *BurnResultParams) BurnResult {
+	return &burnResult{
+		attempts:      params.Attempts,
+		batchID:       params.BatchID,
+		burned:        params.Burned,
+		buryErr:       params.BuryErr,
+		endTime:       params.EndTime,
+		err:           params.Err,
+		incineratorID: params.IncineratorID,
+		providerID:    params.ProviderID,
+		queueWait:     params.QueueWait,
+		sequence:      params.Sequence,
+		startTime:     params.StartTime,
+		stolenFrom:    params.StolenFrom,
+	}
+}
+
+// EndToEndLatencies computes, for every burned Burnable, how long it took from
+// leaving its pile to finishing its burn. Burnables that were taken more than
+// once are measured from the last take before the burn, and those that were
+// never taken are left out.
+func EndToEndLatencies(taken []SupplyTakeResult, burned []BurnResult) map[string]time.Duration {
+	takes := takesByID(taken)
+	latencies := make(map[string]time.Duration, 0)
+
+	for _, result := range burned {
+		id := result.Burned().BurnableID()
+
+		if take, found := lastTake(takes[id], result.EndTime()); found {
+			latencies[id] = result.EndTime().Sub(take.EndTime())
+		}
+	}
+
+	return latencies
+}
+
+// Group take results by the id's of the Suppliables they took.
+func takesByID(taken []SupplyTakeResult) map[string][]SupplyTakeResult {
+	takes := make(map[string][]SupplyTakeResult, 0)
+
+	for _, result := range taken {
+		for _, id := range result.SupplyIDs() {
+			takes[id] = append(takes[id], result)
+		}
+	}
+
+	return takes
+}
+
+// Find the last of a Suppliable's takes that ended no later than some time.
+func lastTake(takes []SupplyTakeResult, before time.Time) (SupplyTakeResult, bool) {
+	var last SupplyTakeResult
+
+	for _, take := range takes {
+		if !take.EndTime().After(before) && (last == nil || take.EndTime().After(last.EndTime())) {
+			last = take
+		}
+	}
+
+	return last, last != nil
 }
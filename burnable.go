@@ -1,13 +1,33 @@
 package goburnbooks
 
+import (
+	"context"
+	"time"
+)
+
 // Burnable represents something that can burn, e.g. books. In the Burn() method,
 // we may implement variable sleep durations to simulate different burning
 // processes (bigger books burn more slowly).
 //
 // For the sake of simplicity, we assume that everything can be burnt eventually,
 // only that some do so longer than others. Therefore, the Burn() method does
-// not error out.
+// not error out. Burnables that may fail should implement FallibleBurnable.
 type Burnable interface {
 	BurnableID() string
 	Burn()
 }
+
+// FallibleBurnable represents a Burnable whose burning may fail. Incinerators
+// call TryBurn() instead of Burn() for these, and retry according to their
+// retry policy. The context is cancelled when the incinerator terminates.
+type FallibleBurnable interface {
+	Burnable
+	TryBurn(ctx context.Context) error
+}
+
+// TimedBurnable represents a Burnable that knows how long it takes to burn, so
+// that its burning can be modelled without actually burning it.
+type TimedBurnable interface {
+	Burnable
+	ExpectedBurnDuration() time.Duration
+}
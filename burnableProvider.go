@@ -1,11 +1,13 @@
 package goburnbooks
 
 import (
+	"context"
 	"fmt"
 )
 
 // BurnableProvider represents a Burnable provider.
 type BurnableProvider interface {
+	Terminator
 	BurnableProviderID() string
 
 	// This channel receives ready signals from incinerators. Only when these
@@ -27,17 +29,22 @@ type BurnableProviderRawParams struct {
 }
 
 // BurnableProviderParams represents all the required parameters to build a
-// provider.
+// provider. If the destination channel is set, the ID of every incinerator
+// that signals ready is sent there before burnables are taken from the source,
+// so that the source knows where they are headed.
 type BurnableProviderParams struct {
 	BurnableProviderRawParams
 	BPLogger                Logger
 	ReceiveBurnableSourceCh <-chan []Burnable
+	SendDestinationCh       chan<- string
 }
 
 // The already providing channel prevents multiple incinerators from sending
 // ready signals to this provider.
 type burnableProvider struct {
+	*lifecycle
 	BurnableProviderParams
+	log                   LeveledLogger
 	receiveProvideReadyCh chan string
 	sendBurnablesCh       chan []Burnable
 }
@@ -59,17 +66,34 @@ func (bp *burnableProvider) BurnableProviderID() string {
 }
 
 func (bp *burnableProvider) loopWork() {
-	logger := bp.BPLogger
+	logger := bp.log
 	receiveProvideReadyCh := bp.receiveProvideReadyCh
+	drainingCh := bp.drainingCh
+	var draining bool
 	var burnables []Burnable
+	var destinationID string
 	var receiveBurnablesCh <-chan []Burnable
 	var sendBurnablesCh chan []Burnable
+	var sendDestinationCh chan<- string
 
 	for {
 		select {
+		case <-bp.ctx.Done():
+			return
+
 		case incID := <-receiveProvideReadyCh:
-			logger.Printf("%v received ready signal from incinerator %v", bp, incID)
+			logger.Debug("received ready signal", Field(FieldPeerID, incID))
 			receiveProvideReadyCh = nil
+
+			if bp.SendDestinationCh != nil {
+				destinationID = incID
+				sendDestinationCh = bp.SendDestinationCh
+			} else {
+				receiveBurnablesCh = bp.ReceiveBurnableSourceCh
+			}
+
+		case sendDestinationCh <- destinationID:
+			sendDestinationCh = nil
 			receiveBurnablesCh = bp.ReceiveBurnableSourceCh
 
 		case burnables = <-receiveBurnablesCh:
@@ -80,18 +104,52 @@ func (bp *burnableProvider) loopWork() {
 			burnables = nil
 			sendBurnablesCh = nil
 			receiveProvideReadyCh = bp.receiveProvideReadyCh
+
+		case <-drainingCh:
+			drainingCh = nil
+			draining = true
+		}
+
+		// Once draining, the source has nothing more to give, so an incinerator
+		// that has signalled ready is handed an empty batch to let it go.
+		if draining {
+			if receiveProvideReadyCh != nil {
+				logger.Info("drained")
+				return
+			}
+
+			if sendBurnablesCh == nil {
+				receiveBurnablesCh = nil
+				sendDestinationCh = nil
+				burnables = []Burnable{}
+				sendBurnablesCh = bp.sendBurnablesCh
+			}
 		}
 	}
 }
 
-// NewBurnableProvider returns a new BurnableProvider.
-func NewBurnableProvider(params *BurnableProviderParams) BurnableProvider {
+// Draining tells the provider that its source has nothing more to give. It
+// still hands over the Burnables it holds, and the returned channel is closed
+// once it no longer owes a batch to any incinerator.
+func (bp *burnableProvider) Drain() <-chan interface{} {
+	return bp.drain()
+}
+
+// NewBurnableProvider returns a new BurnableProvider. Cancelling the context
+// stops the provider.
+func NewBurnableProvider(
+	ctx context.Context,
+	params *BurnableProviderParams,
+) BurnableProvider {
 	bp := &burnableProvider{
+		lifecycle:              newLifecycle(ctx, nil, nil),
 		BurnableProviderParams: *params,
 		receiveProvideReadyCh:  make(chan string),
 		sendBurnablesCh:        make(chan []Burnable),
 	}
 
-	go bp.loopWork()
+	bp.log = Leveled(bp.BPLogger).With(Field(FieldActor, "provider"), Field(FieldActorID, bp.BPID))
+
+	bp.spawn(bp.loopWork)
 	return bp
 }
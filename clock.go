@@ -0,0 +1,251 @@
+package goburnbooks
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock represents a source of time. Every actor that waits for something to
+// happen (burning, travelling or timing out) does so via a Clock, so that
+// simulations can run on virtual time instead of wall time.
+type Clock interface {
+	After(d time.Duration) <-chan time.Time
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// FakeClock represents a Clock that only moves when it is advanced manually.
+type FakeClock interface {
+	Clock
+
+	// Move the clock forward, firing every timer that falls due on the way.
+	Advance(d time.Duration)
+
+	// Move the clock forward to the earliest pending timer and fire it, along
+	// with any other timer due at the same time. Returns false if there is no
+	// pending timer.
+	AdvanceToNext() bool
+
+	// Block until no goroutine has used the clock for the specified wall time,
+	// then advance to the earliest pending timer like AdvanceToNext. Waiting for
+	// the actors woken by the last timer to set their own timers first usually
+	// means that the same scenario advances through the same times.
+	//
+	// This is only a heuristic, since an actor that is busy for longer than the
+	// settle time, e.g. on a loaded machine or under the race detector, looks
+	// idle as well. The clock may then advance before that actor sets its timer,
+	// so virtual durations can vary a little from run to run.
+	AdvanceToNextWhenIdle(settle time.Duration) bool
+
+	// Block until at least the specified number of timers are pending, then
+	// advance to the earliest of them like AdvanceToNext. Unlike
+	// AdvanceToNextWhenIdle, this does not depend on wall time, so it always
+	// advances through the same times as long as the caller knows how many
+	// actors wait on the clock at every step.
+	AdvanceToNextWhenWaiting(waiters int) bool
+
+	// Block until at least the specified number of timers are pending.
+	BlockUntil(waiters int)
+
+	// Get the number of pending timers.
+	Waiters() int
+}
+
+type realClock struct{}
+
+func (rc *realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (rc *realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (rc *realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// NewRealClock returns a Clock that follows wall time.
+func NewRealClock() Clock {
+	return &realClock{}
+}
+
+// Use the real clock if none has been specified.
+func clockOrDefault(clock Clock) Clock {
+	if clock == nil {
+		return NewRealClock()
+	}
+
+	return clock
+}
+
+type fakeTimer struct {
+	deadline time.Time
+	sequence uint64
+	timerCh  chan time.Time
+}
+
+type fakeClock struct {
+	cond     *sync.Cond
+	mutex    sync.Mutex
+	now      time.Time
+	sequence uint64
+	timers   []*fakeTimer
+	touches  uint64
+}
+
+func (fc *fakeClock) After(d time.Duration) <-chan time.Time {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+	fc.touches++
+
+	// The timer channel is buffered so that firing never blocks, even if the
+	// waiter has moved on.
+	timerCh := make(chan time.Time, 1)
+
+	if d <= 0 {
+		timerCh <- fc.now
+		return timerCh
+	}
+
+	fc.sequence++
+
+	fc.timers = append(fc.timers, &fakeTimer{
+		deadline: fc.now.Add(d),
+		sequence: fc.sequence,
+		timerCh:  timerCh,
+	})
+
+	fc.cond.Broadcast()
+	return timerCh
+}
+
+func (fc *fakeClock) Now() time.Time {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+	fc.touches++
+	return fc.now
+}
+
+func (fc *fakeClock) Sleep(d time.Duration) {
+	<-fc.After(d)
+}
+
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+	fc.advanceTo(fc.now.Add(d))
+}
+
+func (fc *fakeClock) AdvanceToNext() bool {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+	return fc.advanceToNext()
+}
+
+func (fc *fakeClock) AdvanceToNextWhenIdle(settle time.Duration) bool {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+	touches := fc.touches
+	idleSince := time.Now()
+
+	// Yield instead of sleeping, so that the goroutines using the clock get to
+	// run without adding the latency of waking up from a sleep to every step.
+	for {
+		fc.mutex.Unlock()
+		runtime.Gosched()
+		fc.mutex.Lock()
+
+		// Only advance if nothing has read the time or set a timer for a while,
+		// otherwise some actor may still be on its way to a timer.
+		if fc.touches != touches {
+			touches = fc.touches
+			idleSince = time.Now()
+		} else if time.Since(idleSince) >= settle {
+			return fc.advanceToNext()
+		}
+	}
+}
+
+func (fc *fakeClock) AdvanceToNextWhenWaiting(waiters int) bool {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	for len(fc.timers) < waiters {
+		fc.cond.Wait()
+	}
+
+	return fc.advanceToNext()
+}
+
+// Only call this while holding the mutex.
+func (fc *fakeClock) advanceToNext() bool {
+	if len(fc.timers) == 0 {
+		return false
+	}
+
+	next := fc.timers[0].deadline
+
+	for _, timer := range fc.timers {
+		if timer.deadline.Before(next) {
+			next = timer.deadline
+		}
+	}
+
+	fc.advanceTo(next)
+	return true
+}
+
+// Fire due timers in deadline order, with ties broken by creation order so
+// that the same sequence of calls always fires in the same order. Only call
+// this while holding the mutex.
+func (fc *fakeClock) advanceTo(now time.Time) {
+	if now.After(fc.now) {
+		fc.now = now
+	}
+
+	sort.Slice(fc.timers, func(i, j int) bool {
+		if fc.timers[i].deadline.Equal(fc.timers[j].deadline) {
+			return fc.timers[i].sequence < fc.timers[j].sequence
+		}
+
+		return fc.timers[i].deadline.Before(fc.timers[j].deadline)
+	})
+
+	fired := 0
+
+	for _, timer := range fc.timers {
+		if timer.deadline.After(fc.now) {
+			break
+		}
+
+		timer.timerCh <- fc.now
+		fired++
+	}
+
+	fc.timers = fc.timers[fired:]
+}
+
+func (fc *fakeClock) BlockUntil(waiters int) {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	for len(fc.timers) < waiters {
+		fc.cond.Wait()
+	}
+}
+
+func (fc *fakeClock) Waiters() int {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+	return len(fc.timers)
+}
+
+// NewFakeClock returns a FakeClock that starts at the specified time.
+func NewFakeClock(start time.Time) FakeClock {
+	fc := &fakeClock{now: start, timers: make([]*fakeTimer, 0)}
+	fc.cond = sync.NewCond(&fc.mutex)
+	return fc
+}
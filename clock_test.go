@@ -0,0 +1,191 @@
+package goburnbooks
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_AdvancingFakeClock_ShouldFireTimersInOrder(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	start := time.Unix(0, 0)
+	clock := NewFakeClock(start)
+	laterCh := clock.After(2 * time.Second)
+	earlierCh := clock.After(time.Second)
+
+	/// When
+	clock.Advance(time.Second)
+
+	/// Then
+	select {
+	case now := <-earlierCh:
+		if !now.Equal(start.Add(time.Second)) {
+			t.Errorf("Should have fired at 1s, but fired at %v", now.Sub(start))
+		}
+
+	default:
+		t.Errorf("Should have fired the earlier timer")
+	}
+
+	select {
+	case <-laterCh:
+		t.Errorf("Should not have fired the later timer yet")
+
+	default:
+	}
+
+	if !clock.AdvanceToNext() || !clock.Now().Equal(start.Add(2*time.Second)) {
+		t.Errorf("Should have advanced to the later timer, got %v", clock.Now())
+	}
+
+	if clock.AdvanceToNext() {
+		t.Errorf("Should not have any timer left")
+	}
+}
+
+func Test_AdvancingFakeClockWithWaiters_ShouldNotOutrunBusyActors(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	start := time.Unix(0, 0)
+	clock := NewFakeClock(start)
+	doneCh := make(chan interface{})
+	defer close(doneCh)
+	wakeCounts := make([]int64, 3)
+
+	// Every actor is busy for a while after waking up, longer than any settle
+	// time would allow, before it waits on the clock again.
+	for ix := range wakeCounts {
+		ix := ix
+
+		go func() {
+			for {
+				select {
+				case <-clock.After(time.Duration(ix+1) * time.Second):
+					time.Sleep(5 * time.Millisecond)
+					atomic.AddInt64(&wakeCounts[ix], 1)
+
+				case <-doneCh:
+					return
+				}
+			}
+		}()
+	}
+
+	/// When
+	for step := 0; step < 6; step++ {
+		clock.AdvanceToNextWhenWaiting(len(wakeCounts))
+	}
+
+	clock.BlockUntil(len(wakeCounts))
+
+	/// Then
+	if now := clock.Now().Sub(start); now != 6*time.Second {
+		t.Errorf("Should have advanced to 6s, got %v", now)
+	}
+
+	for ix, expected := range []int64{6, 3, 2} {
+		if count := atomic.LoadInt64(&wakeCounts[ix]); count != expected {
+			t.Errorf("Actor %d should have woken %d times, got %d", ix, expected, count)
+		}
+	}
+}
+
+func Test_SimulatingOnFakeClock_ShouldNotTakeWallTime(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	clock := NewFakeClock(time.Unix(0, 0))
+	suite := NewDefaultTestSuite()
+	suite.clock = clock
+	players := suite.SetUpSystem()
+	defer players.Terminate()
+	startTime := time.Now()
+
+	/// When
+	report, err := players.WaitAdvancing(clock, suite.waitDuration)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Completed || report.BurnedCount != players.BookCount() {
+		t.Errorf("Should have burned %d, but got %d", players.BookCount(), report.BurnedCount)
+	}
+
+	if report.Duration < suite.tripDelay {
+		t.Errorf("Should have taken at least one trip, but took %v", report.Duration)
+	}
+
+	t.Logf("Took %v of virtual time in %v", report.Duration, time.Since(startTime))
+}
+
+func Test_SimulatingOnFakeClockTwice_ShouldReportTheSame(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	ctx := context.Background()
+	timeout := ScenarioDuration(time.Millisecond)
+
+	book := func(id string, burnDuration time.Duration) BookSpec {
+		return BookSpec{BurnDuration: ScenarioDuration(burnDuration), ID: id}
+	}
+
+	// With a single gopher and incinerator, nothing is left to chance but the
+	// timing of the actors, which the fake clock controls.
+	scenario := &Scenario{
+		Gophers: []GopherSpec{{
+			Capacity:     2,
+			ID:           "0",
+			ReturnTrip:   true,
+			Speed:        1,
+			TakeTimeout:  timeout,
+			TripDuration: ScenarioDuration(time.Hour),
+		}},
+		Incinerators: []IncineratorSpec{{
+			Capacity: 2,
+			ID:       "0",
+			Location: &Location{X: 3, Y: 4},
+		}},
+		SupplyPiles: []SupplyPileSpec{{
+			Books: []BookSpec{
+				book("a", time.Second),
+				book("b", 3*time.Second),
+				book("c", 2*time.Second),
+				book("d", 5*time.Second),
+				book("e", time.Second),
+				book("f", 4*time.Second),
+			},
+			ID:          "0",
+			TakeTimeout: timeout,
+		}},
+	}
+
+	run := func() Report {
+		clock := NewFakeClock(time.Unix(0, 0))
+		simulation := NewSimulation(ctx, scenario.SimulationParams(ctx, &ScenarioParams{Clock: clock}))
+		defer simulation.Terminate()
+		players := &TestPlayers{simulation: simulation}
+		report, err := players.WaitAdvancing(clock, time.Duration(5e9))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return report
+	}
+
+	/// When
+	first := run()
+	second := run()
+
+	/// Then
+	if !first.Completed || first.BurnedCount != len(scenario.SupplyPiles[0].Books) {
+		t.Fatalf("Should have burned every book, got %v", first)
+	}
+
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Should have reported the same twice, got %v and %v", first, second)
+	}
+}
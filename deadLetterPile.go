@@ -0,0 +1,42 @@
+package goburnbooks
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotSuppliable is returned when a Burnable cannot be put into a SupplyPile
+// because it is not a Suppliable.
+var ErrNotSuppliable = errors.New("burnable is not suppliable")
+
+// DeadLetterPile represents a SupplyPile that collects Burnables which could not
+// be burned, so that they can be inspected or supplied again later. Beware that
+// supplying from it back into the same incinerators may loop forever if the
+// failures are permanent.
+type DeadLetterPile interface {
+	FSupplyPile
+	Bury(burnable Burnable) error
+}
+
+type deadLetterPile struct {
+	*supplyPile
+}
+
+func (dlp *deadLetterPile) Bury(burnable Burnable) error {
+	supplies := ExtractSuppliablesFromBurnables(burnable)
+
+	if len(supplies) == 0 {
+		return ErrNotSuppliable
+	}
+
+	return dlp.deposit(supplies...)
+}
+
+// NewDeadLetterPile creates a new DeadLetterPile. Since such a pile usually
+// starts out empty, its capacity should be set to hold all expected failures.
+// It is always restockable, so that it keeps supplying what is buried later.
+func NewDeadLetterPile(ctx context.Context, params *SupplyPileParams) DeadLetterPile {
+	restockable := *params
+	restockable.Restockable = true
+	return &deadLetterPile{supplyPile: newSupplyPile(ctx, &restockable)}
+}
@@ -0,0 +1,76 @@
+package goburnbooks
+
+import "time"
+
+// Deadlined represents something that should be dealt with by some time. The
+// zero time means there is no deadline, and anything that is not Deadlined has
+// none either.
+type Deadlined interface {
+	Deadline() time.Time
+}
+
+// Get the deadline of a Burnable or Suppliable.
+func deadlineOf(value interface{}) time.Time {
+	if deadlined, ok := value.(Deadlined); ok {
+		return deadlined.Deadline()
+	}
+
+	return time.Time{}
+}
+
+// Check whether a deadline comes before another. No deadline comes after all
+// others.
+func earlierDeadline(deadline time.Time, other time.Time) bool {
+	if deadline.IsZero() {
+		return false
+	}
+
+	return other.IsZero() || deadline.Before(other)
+}
+
+// SLASummary counts the burns of Burnables with a deadline, and how many of
+// them missed it. A failed burn always misses its deadline. Misses are broken
+// down by the pile the Burnable was last taken from before the burn, by the
+// gopher that provided it and by the incinerator that burned it.
+type SLASummary struct {
+	Deadlined         int
+	Missed            int
+	PileMisses        map[string]int
+	GopherMisses      map[string]int
+	IncineratorMisses map[string]int
+}
+
+// NewSLASummary summarizes how well a number of burns kept to their deadlines.
+// Misses of Burnables that were never taken are not attributed to any pile.
+func NewSLASummary(taken []SupplyTakeResult, burned []BurnResult) SLASummary {
+	summary := SLASummary{
+		PileMisses:        make(map[string]int, 0),
+		GopherMisses:      make(map[string]int, 0),
+		IncineratorMisses: make(map[string]int, 0),
+	}
+
+	takes := takesByID(taken)
+
+	for _, result := range burned {
+		if result.Deadline().IsZero() {
+			continue
+		}
+
+		summary.Deadlined++
+
+		if result.MetDeadline() {
+			continue
+		}
+
+		summary.Missed++
+		summary.GopherMisses[result.ProviderID()]++
+		summary.IncineratorMisses[result.IncineratorID()]++
+		id := result.Burned().BurnableID()
+
+		if take, found := lastTake(takes[id], result.EndTime()); found {
+			summary.PileMisses[take.PileID()]++
+		}
+	}
+
+	return summary
+}
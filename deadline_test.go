@@ -0,0 +1,117 @@
+package goburnbooks
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const deadlineScenario = `{
+	"supplyPiles": [
+		{
+			"id": "0",
+			"takeTimeout": "1ms",
+			"books": [
+				{"id": "a", "burnDuration": "2s", "deadline": "10s"},
+				{"id": "b", "burnDuration": "2s", "deadline": "3s"},
+				{"id": "c", "burnDuration": "2s", "deadline": "2500ms"},
+				{"id": "d", "burnDuration": "2s"}
+			]
+		}
+	],
+	"gophers": [{"id": "0", "capacity": 4, "takeTimeout": "1ms", "tripDuration": "1ms"}],
+	"incinerators": [{"id": "0", "capacity": 1}]
+}`
+
+func Test_SupplyingFromPile_ShouldPutEarliestDeadlineFirst(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	start := time.Unix(0, 0)
+
+	book := func(id string, priority int, deadline time.Duration) Suppliable {
+		params := &BookParams{ID: id, Priority: priority}
+
+		if deadline > 0 {
+			params.Deadline = start.Add(deadline)
+		}
+
+		return NewBook(params)
+	}
+
+	pile := NewSupplyPile(suite.ctx, &SupplyPileParams{
+		Capacity: 5,
+		ID:       "0",
+		Supply: []Suppliable{
+			book("none", 0, 0),
+			book("late", 0, 2*time.Second),
+			book("early", 0, time.Second),
+			book("urgent", 1, 3*time.Second),
+			book("alsoLate", 0, 2*time.Second),
+		},
+	})
+
+	defer pile.Terminate()
+
+	/// When
+	withdrawn := supplyIDs(pile.Withdraw())
+
+	/// Then
+	expected := []string{"urgent", "early", "late", "alsoLate", "none"}
+
+	if !reflect.DeepEqual(withdrawn, expected) {
+		t.Errorf("Should have supplied %v, got %v", expected, withdrawn)
+	}
+}
+
+func Test_RunningScenarioWithDeadlines_ShouldSummarizeMisses(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	ctx := context.Background()
+	clock := NewFakeClock(time.Unix(0, 0))
+	scenario, err := LoadScenario(strings.NewReader(deadlineScenario))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	simulation := NewSimulation(ctx, scenario.SimulationParams(ctx, &ScenarioParams{Clock: clock}))
+	defer simulation.Terminate()
+	players := &TestPlayers{simulation: simulation}
+
+	/// When
+	concurrent, err := players.WaitAdvancing(clock, time.Duration(5e9))
+	discrete := RunDiscreteSimulation(scenario.DiscreteParams())
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Burning earliest deadline first, only b finishes too late.
+	expected := SLASummary{
+		Deadlined:         3,
+		Missed:            1,
+		PileMisses:        map[string]int{"0": 1},
+		GopherMisses:      map[string]int{"0": 1},
+		IncineratorMisses: map[string]int{"0": 1},
+	}
+
+	if !reflect.DeepEqual(concurrent.SLA, expected) {
+		t.Errorf("Should have summarized %v concurrently, got %v", expected, concurrent.SLA)
+	}
+
+	if !reflect.DeepEqual(discrete.SLA, expected) {
+		t.Errorf("Should have summarized %v discretely, got %v", expected, discrete.SLA)
+	}
+
+	for _, result := range simulation.IncineratorGroup().Burned() {
+		id := result.Burned().BurnableID()
+
+		if result.MetDeadline() != (id != "b") {
+			t.Errorf("Should have met the deadline of %s only if it is not b", id)
+		}
+	}
+}
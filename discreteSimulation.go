@@ -0,0 +1,606 @@
+package goburnbooks
+
+import (
+	"container/heap"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// DiscreteSimulationParams represents all the required parameters to run a
+// discrete event simulation. The actors are described with the same params
+// used to build their concurrent counterparts, though only the capacities and
+// durations are taken into account, along with the locations, speeds,
+// priorities and deadlines. Virtual time starts at the start time, which
+// deadlines are measured against.
+//
+// The dispatch strategy decides which of the ready incinerators receives each
+// batch, and the selection strategy decides which pile each gopher takes from.
+// Without them, an incinerator or pile is picked at random from the seed. Loads
+// are always routed to incinerators that accept them, as if by a dispatcher,
+// and Burnables that no incinerator accepts fail.
+//
+// Like in the concurrent runtime, every incinerator signals ready to every
+// gopher on its own, so it may hold back a batch from each of them at once.
+// Some things are left out however, so reports only agree within a tolerance.
+// A gopher takes its next load only once it has delivered the last, instead
+// of while it is still on its way, and it never times out waiting for a pile,
+// which always loads it right away. Piles never age the priorities of their
+// supplies, which would not change their order anyway, since nothing is
+// restocked and every supply has waited equally long. Incinerators never steal
+// work from each other.
+type DiscreteSimulationParams struct {
+	Dispatch     DispatchStrategy
+	Gophers      []GopherParams
+	Incinerators []IncineratorParams
+	Seed         int64
+	Selection    SelectionStrategy
+	Start        time.Time
+	SupplyPiles  []SupplyPileParams
+}
+
+// A discrete event happens at a point in virtual time. Events that happen at
+// the same time are processed in the order they were scheduled.
+type discreteEvent struct {
+	action   func()
+	at       time.Duration
+	sequence uint64
+}
+
+type discreteEventQueue []*discreteEvent
+
+func (deq discreteEventQueue) Len() int {
+	return len(deq)
+}
+
+func (deq discreteEventQueue) Less(i, j int) bool {
+	if deq[i].at == deq[j].at {
+		return deq[i].sequence < deq[j].sequence
+	}
+
+	return deq[i].at < deq[j].at
+}
+
+func (deq discreteEventQueue) Swap(i, j int) {
+	deq[i], deq[j] = deq[j], deq[i]
+}
+
+func (deq *discreteEventQueue) Push(event interface{}) {
+	*deq = append(*deq, event.(*discreteEvent))
+}
+
+func (deq *discreteEventQueue) Pop() interface{} {
+	old := *deq
+	event := old[len(old)-1]
+	*deq = old[:len(old)-1]
+	return event
+}
+
+// The supplies of a pile are sorted by priority and deadline up front, since
+// nothing is ever restocked.
+type discretePile struct {
+	*SupplyPileParams
+	next   int
+	supply []Suppliable
+}
+
+func (dp *discretePile) remaining() int {
+	return len(dp.supply) - dp.next
+}
+
+type discreteGopher struct {
+	*GopherParams
+	load   []Burnable
+	origin Location
+	trip   time.Duration
+}
+
+// A batch keeps an incinerator from signalling ready to the gopher that
+// delivered it until enough of its weight has been burned, just like in the
+// concurrent runtime.
+type discreteBatch struct {
+	remaining uint
+}
+
+type discreteBurn struct {
+	batch      *discreteBatch
+	burnable   Burnable
+	providerID string
+	queuedAt   time.Duration
+	startedAt  time.Duration
+}
+
+// Get the priority of a burn after it has waited until now, like a burnTicket.
+func (db *discreteBurn) agedPriority(now time.Duration, aging time.Duration) int {
+	if aging <= 0 {
+		return priorityOf(db.burnable)
+	}
+
+	return priorityOf(db.burnable) + int((now-db.queuedAt)/aging)
+}
+
+// The weights burning and queued are kept alongside the queue, since they are
+// needed for every dispatch.
+//
+// Like in the concurrent runtime, an incinerator is ready to every gopher on
+// its own, so it may hold a batch from each of them at once. A gopher that is
+// on its way has used up the ready signal, and so does a batch that is held
+// back until enough of it has been burned. The time each gopher was last
+// signalled ready is kept as a sequence, so that the incinerators that have
+// been ready the longest come first.
+type discreteIncinerator struct {
+	*IncineratorParams
+	burning uint
+	holding map[string]*discreteBatch
+	pending time.Duration
+	queue   []*discreteBurn
+	queued  uint
+	readyAt map[string]uint64
+}
+
+// Check whether an incinerator has signalled ready to a gopher.
+func (di *discreteIncinerator) readyFor(gopher *discreteGopher) bool {
+	_, holding := di.holding[gopher.BPID]
+	return !holding
+}
+
+func (di *discreteIncinerator) load() IncineratorLoad {
+	return IncineratorLoad{
+		Burning:         di.burning,
+		Capacity:        di.Capacity,
+		ID:              di.ID,
+		PendingDuration: di.pending,
+		Queued:          di.queued,
+	}
+}
+
+type discreteEngine struct {
+	auditor        Auditor
+	dispatch       DispatchStrategy
+	events         discreteEventQueue
+	gophers        []*discreteGopher
+	incinerators   []*discreteIncinerator
+	now            time.Duration
+	piles          []*discretePile
+	random         *rand.Rand
+	report         Report
+	selection      SelectionStrategy
+	sequence       uint64
+	start          time.Time
+	waitingGophers []*discreteGopher
+
+	// Every result is kept to summarize deadlines at the end.
+	burned []BurnResult
+	taken  []SupplyTakeResult
+}
+
+func (de *discreteEngine) schedule(after time.Duration, action func()) {
+	de.sequence++
+
+	heap.Push(&de.events, &discreteEvent{
+		action:   action,
+		at:       de.now + after,
+		sequence: de.sequence,
+	})
+}
+
+// A gopher that is ready to take goes to a pile that still has supplies, picked
+// by the selection strategy if there is one. Otherwise, the pile is picked at
+// random, which mirrors the race between piles in the concurrent runtime. If
+// the pile cannot fill the gopher up, it only hands over after its take
+// timeout.
+func (de *discreteEngine) takeSupply(gopher *discreteGopher) {
+	piles := make([]*discretePile, 0)
+
+	for _, pile := range de.piles {
+		if pile.remaining() > 0 {
+			piles = append(piles, pile)
+		}
+	}
+
+	if len(piles) == 0 {
+		return
+	}
+
+	var pile *discretePile
+
+	if de.selection == nil {
+		pile = piles[de.random.Intn(len(piles))]
+	} else {
+		stocks := make([]PileStock, len(piles))
+
+		for ix, candidate := range piles {
+			stocks[ix] = PileStock{ID: candidate.ID, Remaining: candidate.remaining()}
+		}
+
+		pile = piles[de.selection.Pick(gopher.STID, stocks)]
+	}
+
+	count := 0
+	weight := uint(0)
+	wait := time.Duration(0)
+
+	for count < pile.remaining() && (gopher.Cap == 0 || weight < gopher.Cap) {
+		next := weightOf(pile.supply[pile.next+count])
+
+		if !fitsWeight(weight, next, gopher.Cap) {
+			break
+		}
+
+		count++
+		weight += next
+	}
+
+	// Only a gopher that ran out of supplies before filling up waits, since
+	// loading stops right away once the next supply does not fit.
+	if count == pile.remaining() && (gopher.Cap == 0 || weight < gopher.Cap) {
+		wait = pile.TakeTimeout
+	}
+
+	supplies := pile.supply[pile.next : pile.next+count]
+	pile.next += count
+	gopher.load = ExtractBurnablesFromSuppliables(supplies...)
+	gopher.origin = pile.Location
+	taken := NewSupplyTakeResult(&SupplyTakeResultParams{
+		EndTime:   de.start.Add(de.now + wait),
+		Metadata:  suppliesMetadata(supplies),
+		PileID:    pile.ID,
+		StartTime: de.start.Add(de.now),
+		SupplyIDs: supplyIDs(supplies),
+		TakerID:   gopher.STID,
+	})
+
+	de.auditor.RecordTaken(taken)
+	de.taken = append(de.taken, taken)
+	de.report.SupplyPileContrib[pile.ID] += count
+	de.report.SupplyTakerContrib[gopher.STID] += count
+
+	// A gopher with a speed needs to know where it is headed, so it waits for
+	// an incinerator before setting out, like in the concurrent runtime.
+	if gopher.Speed > 0 {
+		de.schedule(wait, func() {
+			de.arrive(gopher)
+		})
+
+		return
+	}
+
+	gopher.trip = gopher.TripDuration
+
+	de.schedule(wait+gopher.trip, func() {
+		de.arrive(gopher)
+	})
+}
+
+// Send a gopher off to an incinerator that has signalled ready to it, unless
+// it is already there.
+func (de *discreteEngine) head(gopher *discreteGopher, inc *discreteIncinerator) {
+	if gopher.Speed <= 0 {
+		de.deliver(gopher, inc)
+		return
+	}
+
+	gopher.trip = gopher.tripDuration(gopher.origin, inc.ID)
+
+	de.schedule(gopher.trip, func() {
+		de.deliver(gopher, inc)
+	})
+}
+
+// Only incinerators that are ready to a gopher and can take some of its load
+// are candidates, like with a dispatcher in the concurrent runtime.
+func (de *discreteEngine) arrive(gopher *discreteGopher) {
+	candidates := make([]*discreteIncinerator, 0, len(de.incinerators))
+
+	for _, inc := range de.incinerators {
+		if inc.readyFor(gopher) && len(de.split(inc, gopher.load)) > 0 {
+			candidates = append(candidates, inc)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].readyAt[gopher.BPID] < candidates[j].readyAt[gopher.BPID]
+	})
+
+	if len(candidates) == 0 {
+		de.waitingGophers = append(de.waitingGophers, gopher)
+		return
+	}
+
+	var index int
+
+	if de.dispatch == nil {
+		index = de.random.Intn(len(candidates))
+	} else {
+		loads := make([]IncineratorLoad, len(candidates))
+
+		for ix, inc := range candidates {
+			loads[ix] = inc.load()
+		}
+
+		index = de.dispatch.Pick(gopher.load, loads)
+	}
+
+	incinerator := candidates[index]
+	incinerator.holding[gopher.BPID] = nil
+	de.head(gopher, incinerator)
+}
+
+// Check whether a Burnable can go to an incinerator, because it accepts the
+// Burnable or no incinerator does.
+func (de *discreteEngine) routable(inc *discreteIncinerator, burnable Burnable) bool {
+	if inc.accepts(burnable) {
+		return true
+	}
+
+	for _, other := range de.incinerators {
+		if other.accepts(burnable) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Get the part of a load that can go to an incinerator.
+func (de *discreteEngine) split(inc *discreteIncinerator, load []Burnable) []Burnable {
+	part := make([]Burnable, 0, len(load))
+
+	for _, burnable := range load {
+		if de.routable(inc, burnable) {
+			part = append(part, burnable)
+		}
+	}
+
+	return part
+}
+
+// A gopher only hands over the part of its load that the incinerator can take,
+// and carries the rest on to the next one.
+func (de *discreteEngine) deliver(gopher *discreteGopher, inc *discreteIncinerator) {
+	accepted := make([]Burnable, 0, len(gopher.load))
+	rest := make([]Burnable, 0)
+
+	for _, burnable := range gopher.load {
+		switch {
+		case !de.routable(inc, burnable):
+			rest = append(rest, burnable)
+
+		case inc.accepts(burnable):
+			accepted = append(accepted, burnable)
+
+		default:
+			de.reject(gopher, inc, burnable)
+		}
+	}
+
+	batch := &discreteBatch{remaining: totalWeight(accepted...)}
+	inc.queued += batch.remaining
+	de.report.ProviderContrib[gopher.BPID] += len(gopher.load) - len(rest)
+	inc.pending += expectedBurnDuration(accepted...)
+
+	for _, burnable := range accepted {
+		inc.queue = append(inc.queue, &discreteBurn{
+			batch:      batch,
+			burnable:   burnable,
+			providerID: gopher.BPID,
+			queuedAt:   de.now,
+		})
+	}
+
+	gopher.load = rest
+
+	if batch.remaining == 0 {
+		de.signalReady(inc, gopher.BPID)
+	} else {
+		inc.holding[gopher.BPID] = batch
+	}
+
+	de.startBurning(inc)
+
+	if len(rest) > 0 {
+		de.arrive(gopher)
+		return
+	}
+
+	if !gopher.ReturnTrip {
+		de.takeSupply(gopher)
+		return
+	}
+
+	de.schedule(gopher.trip, func() {
+		de.takeSupply(gopher)
+	})
+}
+
+func (de *discreteEngine) startBurning(inc *discreteIncinerator) {
+	for len(inc.queue) > 0 {
+		picked := 0
+		highest := inc.queue[0].agedPriority(de.now, inc.PriorityAging)
+
+		for ix := 1; ix < len(inc.queue); ix++ {
+			priority := inc.queue[ix].agedPriority(de.now, inc.PriorityAging)
+
+			if priority > highest || (priority == highest && earlierDeadline(
+				deadlineOf(inc.queue[ix].burnable),
+				deadlineOf(inc.queue[picked].burnable),
+			)) {
+				picked = ix
+				highest = priority
+			}
+		}
+
+		burn := inc.queue[picked]
+		weight := weightOf(burn.burnable)
+
+		if !fitsWeight(inc.burning, weight, inc.Capacity) {
+			return
+		}
+
+		burn.startedAt = de.now
+		inc.queue = append(inc.queue[:picked], inc.queue[picked+1:]...)
+		inc.burning += weight
+		inc.queued -= weight
+		duration := expectedBurnDuration(burn.burnable)
+
+		de.schedule(duration, func() {
+			de.finishBurning(inc, burn)
+		})
+	}
+}
+
+func (de *discreteEngine) finishBurning(inc *discreteIncinerator, burn *discreteBurn) {
+	weight := weightOf(burn.burnable)
+	inc.burning -= weight
+	inc.pending -= expectedBurnDuration(burn.burnable)
+	burn.batch.remaining -= weight
+	de.report.BurnedCount++
+
+	// The run is over once the last book has burned, even if gophers are still
+	// on their way back.
+	de.report.Duration = de.now
+	de.report.BurnedIDs[burn.burnable.BurnableID()]++
+	de.report.IncineratorContrib[inc.ID]++
+
+	result := NewBurnResult(&BurnResultParams{
+		Attempts:      1,
+		Burned:        burn.burnable,
+		EndTime:       de.start.Add(de.now),
+		IncineratorID: inc.ID,
+		ProviderID:    burn.providerID,
+		QueueWait:     burn.startedAt - burn.queuedAt,
+		StartTime:     de.start.Add(burn.queuedAt),
+	})
+
+	de.auditor.RecordBurned(result)
+	de.burned = append(de.burned, result)
+
+	if inc.holding[burn.providerID] == burn.batch &&
+		(burn.batch.remaining < inc.MinCapacity || burn.batch.remaining == 0) {
+		de.signalReady(inc, burn.providerID)
+	}
+
+	de.startBurning(inc)
+}
+
+// Fail a Burnable that no incinerator accepts, like an incinerator in the
+// concurrent runtime does.
+func (de *discreteEngine) reject(gopher *discreteGopher, inc *discreteIncinerator, burnable Burnable) {
+	de.report.FailedCount++
+	de.report.FailedIDs[burnable.BurnableID()]++
+	de.report.IncineratorContrib[inc.ID]++
+
+	result := NewBurnResult(&BurnResultParams{
+		Burned:        burnable,
+		EndTime:       de.start.Add(de.now),
+		Err:           ErrNotAccepted,
+		IncineratorID: inc.ID,
+		ProviderID:    gopher.BPID,
+		StartTime:     de.start.Add(de.now),
+	})
+
+	de.auditor.RecordBurned(result)
+	de.burned = append(de.burned, result)
+}
+
+// The incinerator signals ready to a gopher again, which heads there right away
+// if it has been waiting for an incinerator that can take something from it.
+func (de *discreteEngine) signalReady(inc *discreteIncinerator, providerID string) {
+	de.sequence++
+	delete(inc.holding, providerID)
+	inc.readyAt[providerID] = de.sequence
+
+	for ix, gopher := range de.waitingGophers {
+		if gopher.BPID == providerID && len(de.split(inc, gopher.load)) > 0 {
+			de.waitingGophers = append(de.waitingGophers[:ix], de.waitingGophers[ix+1:]...)
+			inc.holding[providerID] = nil
+			de.head(gopher, inc)
+			return
+		}
+	}
+}
+
+func (de *discreteEngine) run() Report {
+	for _, gopher := range de.gophers {
+		de.takeSupply(gopher)
+	}
+
+	for de.events.Len() > 0 {
+		event := heap.Pop(&de.events).(*discreteEvent)
+		de.now = event.at
+		event.action()
+	}
+
+	de.report.Completed = de.report.BurnedCount+de.report.FailedCount == de.report.SupplyCount
+	de.report.SLA = NewSLASummary(de.taken, de.burned)
+	de.report.Violations = de.auditor.Reconcile().Violations()
+	return de.report
+}
+
+// RunDiscreteSimulation runs the whole system on a single goroutine, using an
+// event queue and virtual time instead of channels. Since nothing ever waits
+// on wall time, it can process millions of books in seconds, and the same
+// params always produce the same report.
+//
+// Burn durations come from Suppliables that are TimedBurnables, and burns are
+// assumed never to fail. Every take and burn is audited like in the concurrent
+// runtime.
+func RunDiscreteSimulation(params *DiscreteSimulationParams) Report {
+	engine := &discreteEngine{
+		auditor:   NewAuditor(&AuditorParams{}),
+		dispatch:  params.Dispatch,
+		events:    make(discreteEventQueue, 0),
+		random:    rand.New(rand.NewSource(params.Seed)),
+		selection: params.Selection,
+		start:     params.Start,
+		report: Report{
+			BurnedIDs:          make(map[string]int, 0),
+			FailedIDs:          make(map[string]int, 0),
+			IncineratorContrib: make(map[string]int, 0),
+			ProviderContrib:    make(map[string]int, 0),
+			SupplyPileContrib:  make(map[string]int, 0),
+			SupplyTakerContrib: make(map[string]int, 0),
+		},
+	}
+
+	for ix := range params.SupplyPiles {
+		pile := &discretePile{SupplyPileParams: &params.SupplyPiles[ix]}
+		pile.supply = append([]Suppliable{}, pile.Supply...)
+
+		sort.SliceStable(pile.supply, func(i, j int) bool {
+			if priority := priorityOf(pile.supply[i]); priority != priorityOf(pile.supply[j]) {
+				return priority > priorityOf(pile.supply[j])
+			}
+
+			return earlierDeadline(deadlineOf(pile.supply[i]), deadlineOf(pile.supply[j]))
+		})
+
+		engine.piles = append(engine.piles, pile)
+		engine.report.SupplyCount += len(pile.Supply)
+		engine.auditor.RecordSupply(pile.ID, supplyIDs(pile.Supply)...)
+	}
+
+	destinations := make(map[string]Location, len(params.Incinerators))
+
+	for ix := range params.Incinerators {
+		inc := &discreteIncinerator{
+			IncineratorParams: &params.Incinerators[ix],
+			holding:           make(map[string]*discreteBatch, 0),
+			readyAt:           make(map[string]uint64, 0),
+		}
+
+		destinations[inc.ID] = inc.Location
+		engine.incinerators = append(engine.incinerators, inc)
+	}
+
+	// Like a simulation, the engine lets gophers know where its incinerators
+	// are, without touching the params it was given.
+	for ix := range params.Gophers {
+		gopherParams := params.Gophers[ix]
+		gopherParams.Destinations = withDestinations(gopherParams.Destinations, destinations)
+		gopher := &discreteGopher{GopherParams: &gopherParams}
+		engine.gophers = append(engine.gophers, gopher)
+	}
+
+	return engine.run()
+}
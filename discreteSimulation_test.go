@@ -0,0 +1,139 @@
+package goburnbooks
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_RunningDiscreteSimulationWithSameSeed_ShouldBeReproducible(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+
+	/// When
+	report1 := RunDiscreteSimulation(suite.DiscreteParams(1))
+	report2 := RunDiscreteSimulation(suite.DiscreteParams(1))
+
+	/// Then
+	if !report1.Completed || report1.BurnedCount != int(suite.TotalSupplyCount()) {
+		t.Errorf("Should have burned %d, got %d", suite.TotalSupplyCount(), report1.BurnedCount)
+	}
+
+	if !reflect.DeepEqual(report1, report2) {
+		t.Errorf("Should have produced the same report, got %v and %v", report1, report2)
+	}
+
+	for id, count := range report1.BurnedIDs {
+		if count != 1 {
+			t.Errorf("Should have burned %s once, but burned %d times", id, count)
+		}
+	}
+}
+
+func Test_RunningDiscreteSimulation_ShouldMatchConcurrentRuntime(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	clock := NewFakeClock(time.Unix(0, 0))
+	suite := NewDefaultTestSuite()
+	suite.clock = clock
+	players := suite.SetUpSystem()
+	defer players.Terminate()
+
+	/// When
+	expected, err := players.WaitAdvancing(clock, suite.waitDuration)
+	actual := RunDiscreteSimulation(suite.DiscreteParams(1))
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, difference := range CompareReports(expected, actual, 0.05) {
+		t.Error(difference)
+	}
+
+	t.Logf("Took %v concurrently and %v discretely", expected.Duration, actual.Duration)
+}
+
+func Test_RunningLargeDiscreteSimulation_ShouldBeFast(t *testing.T) {
+	/// Setup
+	// Not parallel, so that it does not slow down the fairness tests.
+	suite := NewDefaultTestSuite()
+	suite.supplyPerPileCount = 100000
+	params := suite.DiscreteParams(1)
+
+	// This takes a few seconds, or a few times that with the race detector, but
+	// would take hours if every event cost time in proportion to the books.
+	maxWallTime := 30 * time.Second
+	startTime := time.Now()
+
+	/// When
+	report := RunDiscreteSimulation(params)
+	wallTime := time.Since(startTime)
+
+	/// Then
+	if !report.Completed || report.BurnedCount != int(suite.TotalSupplyCount()) {
+		t.Errorf("Should have burned %d, got %d", suite.TotalSupplyCount(), report.BurnedCount)
+	}
+
+	if wallTime > maxWallTime {
+		t.Errorf("Should have taken at most %v, got %v", maxWallTime, wallTime)
+	}
+
+	t.Logf("Took %v of virtual time in %v", report.Duration, wallTime)
+}
+
+func Test_RunningDiscreteSimulationWithManyGophers_ShouldHoldBatchFromEach(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	ctx := context.Background()
+	clock := NewFakeClock(time.Unix(0, 0))
+	burnDuration := ScenarioDuration(time.Second)
+	tripDuration := ScenarioDuration(time.Millisecond)
+
+	// The incinerator has room for both books, so it should burn them side by
+	// side instead of waiting for one gopher's batch before taking the other's.
+	scenario := &Scenario{
+		Gophers: []GopherSpec{
+			{Capacity: 1, ID: "0", TakeTimeout: tripDuration, TripDuration: tripDuration},
+			{Capacity: 1, ID: "1", TakeTimeout: tripDuration, TripDuration: tripDuration},
+		},
+		Incinerators: []IncineratorSpec{{Capacity: 2, ID: "0", MinCapacity: 2}},
+		SupplyPiles: []SupplyPileSpec{{
+			Books: []BookSpec{
+				{BurnDuration: burnDuration, ID: "a"},
+				{BurnDuration: burnDuration, ID: "b"},
+			},
+			ID:          "0",
+			TakeTimeout: tripDuration,
+		}},
+	}
+
+	simulation := NewSimulation(ctx, scenario.SimulationParams(ctx, &ScenarioParams{Clock: clock}))
+	defer simulation.Terminate()
+	players := &TestPlayers{simulation: simulation}
+
+	/// When
+	report, err := players.WaitAdvancing(clock, time.Duration(5e9))
+	discrete := RunDiscreteSimulation(scenario.DiscreteParams())
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := time.Duration(tripDuration + burnDuration)
+
+	if discrete.Duration != expected {
+		t.Errorf("Should have taken %v discretely, got %v", expected, discrete.Duration)
+	}
+
+	// A gopher may take the second book before the other one is ready to, in
+	// which case the incinerator rightly holds back its second batch until the
+	// first has burned.
+	if report.ProviderContrib["0"] == 1 && report.Duration != expected {
+		t.Errorf("Should have taken %v concurrently, got %v", expected, report.Duration)
+	}
+}
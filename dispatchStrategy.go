@@ -0,0 +1,235 @@
+package goburnbooks
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// IncineratorLoad describes how busy an incinerator is at some point in time.
+// Like the capacity, everything is measured in weight.
+type IncineratorLoad struct {
+	Capacity uint
+	ID       string
+
+	// The weight of the Burnables being burned.
+	Burning uint
+
+	// The weight of the Burnables that have been received, but are still
+	// waiting for enough free capacity to burn.
+	Queued uint
+
+	// The expected time it takes to burn everything that is burning or queued,
+	// as if it were burned one at a time. Only TimedBurnables are counted.
+	PendingDuration time.Duration
+}
+
+// Occupancy is the weight of the Burnables burning or queued per unit of
+// capacity.
+func (il IncineratorLoad) Occupancy() float64 {
+	return float64(il.Burning+il.Queued) / float64(il.capacity())
+}
+
+// ExpectedFinish estimates how long it takes to burn everything pending, as
+// well as a batch on top of that, with the whole capacity in use.
+func (il IncineratorLoad) ExpectedFinish(batch []Burnable) time.Duration {
+	total := il.PendingDuration + expectedBurnDuration(batch...)
+	return total / time.Duration(il.capacity())
+}
+
+func (il IncineratorLoad) capacity() uint {
+	if il.Capacity == 0 {
+		return 1
+	}
+
+	return il.Capacity
+}
+
+// Sum up the expected burn durations of TimedBurnables, ignoring the rest.
+func expectedBurnDuration(burnables ...Burnable) time.Duration {
+	var total time.Duration
+
+	for _, burnable := range burnables {
+		if timed, ok := burnable.(TimedBurnable); ok {
+			total += timed.ExpectedBurnDuration()
+		}
+	}
+
+	return total
+}
+
+// DispatchStrategy decides which of the incinerators that have signalled ready
+// receives a batch. The candidates are never empty and are listed in the order
+// they signalled ready, and the returned value is an index into them. Since an
+// incinerator group calls this for every provider at once, implementations must
+// be safe for concurrent use.
+type DispatchStrategy interface {
+	Pick(batch []Burnable, candidates []IncineratorLoad) int
+}
+
+// Pick the first candidate that has the lowest score.
+func pickLowest(candidates []IncineratorLoad, score func(load IncineratorLoad) float64) int {
+	picked := 0
+	lowest := score(candidates[0])
+
+	for ix := 1; ix < len(candidates); ix++ {
+		if value := score(candidates[ix]); value < lowest {
+			picked = ix
+			lowest = value
+		}
+	}
+
+	return picked
+}
+
+// The incinerator that signalled ready first is picked, as if they raced for
+// the batch. A group uses this to route batches by category when no strategy
+// is specified.
+type firstReadyDispatch struct{}
+
+func (frd firstReadyDispatch) Pick(batch []Burnable, candidates []IncineratorLoad) int {
+	return 0
+}
+
+// Every incinerator is picked in turn, skipping those that are not ready.
+type roundRobinDispatch struct {
+	mutex    sync.Mutex
+	pickedAt map[string]uint64
+	turn     uint64
+}
+
+// NewRoundRobinDispatch returns a DispatchStrategy that picks the candidate
+// that was picked the longest time ago, or never.
+func NewRoundRobinDispatch() DispatchStrategy {
+	return &roundRobinDispatch{pickedAt: make(map[string]uint64, 0)}
+}
+
+func (rrd *roundRobinDispatch) Pick(batch []Burnable, candidates []IncineratorLoad) int {
+	rrd.mutex.Lock()
+	defer rrd.mutex.Unlock()
+
+	picked := pickLowest(candidates, func(load IncineratorLoad) float64 {
+		return float64(rrd.pickedAt[load.ID])
+	})
+
+	rrd.turn++
+	rrd.pickedAt[candidates[picked].ID] = rrd.turn
+	return picked
+}
+
+type leastLoadedDispatch struct{}
+
+// NewLeastLoadedDispatch returns a DispatchStrategy that picks the candidate
+// with the lowest occupancy.
+func NewLeastLoadedDispatch() DispatchStrategy {
+	return leastLoadedDispatch{}
+}
+
+func (lld leastLoadedDispatch) Pick(batch []Burnable, candidates []IncineratorLoad) int {
+	return pickLowest(candidates, func(load IncineratorLoad) float64 {
+		return load.Occupancy()
+	})
+}
+
+type shortestFinishDispatch struct{}
+
+// NewShortestFinishDispatch returns a DispatchStrategy that picks the candidate
+// that is expected to finish the batch first.
+func NewShortestFinishDispatch() DispatchStrategy {
+	return shortestFinishDispatch{}
+}
+
+func (sfd shortestFinishDispatch) Pick(batch []Burnable, candidates []IncineratorLoad) int {
+	return pickLowest(candidates, func(load IncineratorLoad) float64 {
+		return float64(load.ExpectedFinish(batch))
+	})
+}
+
+// This is the smooth weighted round robin used by nginx: every candidate gains
+// its capacity, and the one with the most is picked and pays back the total.
+type weightedDispatch struct {
+	current map[string]int64
+	mutex   sync.Mutex
+}
+
+// NewWeightedDispatch returns a DispatchStrategy that picks candidates in turn,
+// in proportion to their capacities.
+func NewWeightedDispatch() DispatchStrategy {
+	return &weightedDispatch{current: make(map[string]int64, 0)}
+}
+
+func (wd *weightedDispatch) Pick(batch []Burnable, candidates []IncineratorLoad) int {
+	wd.mutex.Lock()
+	defer wd.mutex.Unlock()
+	var total int64
+
+	for _, load := range candidates {
+		weight := int64(load.capacity())
+		wd.current[load.ID] += weight
+		total += weight
+	}
+
+	picked := pickLowest(candidates, func(load IncineratorLoad) float64 {
+		return -float64(wd.current[load.ID])
+	})
+
+	wd.current[candidates[picked].ID] -= total
+	return picked
+}
+
+type randomDispatch struct {
+	mutex  sync.Mutex
+	random *rand.Rand
+}
+
+// NewRandomDispatch returns a DispatchStrategy that picks candidates at random,
+// in a sequence that is determined by the seed.
+func NewRandomDispatch(seed int64) DispatchStrategy {
+	return &randomDispatch{random: rand.New(rand.NewSource(seed))}
+}
+
+func (rd *randomDispatch) Pick(batch []Burnable, candidates []IncineratorLoad) int {
+	rd.mutex.Lock()
+	defer rd.mutex.Unlock()
+	return rd.random.Intn(len(candidates))
+}
+
+// These are the names of the supported dispatch strategies. Racing leaves the
+// choice to the scheduler, which is what happens without a strategy.
+const (
+	DispatchLeastLoaded    = "leastLoaded"
+	DispatchRace           = "race"
+	DispatchRandom         = "random"
+	DispatchRoundRobin     = "roundRobin"
+	DispatchShortestFinish = "shortestFinish"
+	DispatchWeighted       = "weighted"
+)
+
+// ParseDispatchStrategy returns the DispatchStrategy with the specified name,
+// seeding it if it is random. An empty name means racing, for which the
+// strategy is nil.
+func ParseDispatchStrategy(name string, seed int64) (DispatchStrategy, error) {
+	switch name {
+	case "", DispatchRace:
+		return nil, nil
+
+	case DispatchLeastLoaded:
+		return NewLeastLoadedDispatch(), nil
+
+	case DispatchRandom:
+		return NewRandomDispatch(seed), nil
+
+	case DispatchRoundRobin:
+		return NewRoundRobinDispatch(), nil
+
+	case DispatchShortestFinish:
+		return NewShortestFinishDispatch(), nil
+
+	case DispatchWeighted:
+		return NewWeightedDispatch(), nil
+
+	default:
+		return nil, fmt.Errorf("unknown dispatch strategy %q", name)
+	}
+}
@@ -0,0 +1,243 @@
+package goburnbooks
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func Test_PickingRoundRobin_ShouldTakeTurnsAmongReady(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	strategy := NewRoundRobinDispatch()
+	all := []IncineratorLoad{{ID: "0"}, {ID: "1"}, {ID: "2"}}
+	picked := make([]string, 0)
+
+	/// When
+	for ix := 0; ix < 4; ix++ {
+		picked = append(picked, all[strategy.Pick(nil, all)].ID)
+	}
+
+	// The last pick was 0, so 1 has waited the longest.
+	someReady := []IncineratorLoad{all[2], all[1]}
+	picked = append(picked, someReady[strategy.Pick(nil, someReady)].ID)
+
+	/// Then
+	expected := []string{"0", "1", "2", "0", "1"}
+
+	if !reflect.DeepEqual(picked, expected) {
+		t.Errorf("Should have picked %v, got %v", expected, picked)
+	}
+}
+
+func Test_PickingByLoad_ShouldPreferLessBusyIncinerators(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	batch := []Burnable{NewBook(&BookParams{BurnDuration: 4 * time.Second})}
+
+	candidates := []IncineratorLoad{
+		{Burning: 2, Capacity: 2, ID: "0", PendingDuration: 2 * time.Second},
+		{Burning: 1, Capacity: 1, ID: "1", Queued: 1, PendingDuration: 8 * time.Second},
+		{Burning: 3, Capacity: 8, ID: "2", PendingDuration: 12 * time.Second},
+	}
+
+	/// When
+	leastLoaded := NewLeastLoadedDispatch().Pick(batch, candidates)
+	shortestFinish := NewShortestFinishDispatch().Pick(batch, candidates)
+	finishes := make([]time.Duration, len(candidates))
+
+	for ix, load := range candidates {
+		finishes[ix] = load.ExpectedFinish(batch)
+	}
+
+	/// Then
+	if leastLoaded != 2 {
+		t.Errorf("Should have picked the lowest occupancy, got %d", leastLoaded)
+	}
+
+	if shortestFinish != 2 {
+		t.Errorf("Should have picked the shortest finish among %v, got %d", finishes, shortestFinish)
+	}
+
+	if finishes[0] != 3*time.Second || finishes[1] != 12*time.Second {
+		t.Errorf("Should have spread pending work over the capacity, got %v", finishes)
+	}
+}
+
+func Test_PickingWeighted_ShouldFollowCapacities(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	strategy := NewWeightedDispatch()
+	candidates := []IncineratorLoad{{Capacity: 3, ID: "0"}, {Capacity: 1, ID: "1"}}
+	contrib := make(map[string]int, 0)
+	picked := make([]string, 0)
+
+	/// When
+	for ix := 0; ix < 8; ix++ {
+		id := candidates[strategy.Pick(nil, candidates)].ID
+		contrib[id]++
+		picked = append(picked, id)
+	}
+
+	/// Then
+	if contrib["0"] != 6 || contrib["1"] != 2 {
+		t.Errorf("Should have picked in proportion to capacity, got %v", contrib)
+	}
+
+	if picked[0] != "0" || picked[1] != "0" || picked[2] != "1" {
+		t.Errorf("Should have interleaved the picks, got %v", picked)
+	}
+}
+
+func Test_ParsingDispatchStrategy_ShouldSeedRandomAndRejectUnknown(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	candidates := []IncineratorLoad{{ID: "0"}, {ID: "1"}, {ID: "2"}, {ID: "3"}}
+
+	picks := func(seed int64) []int {
+		strategy, err := ParseDispatchStrategy(DispatchRandom, seed)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		picked := make([]int, 0)
+
+		for ix := 0; ix < 20; ix++ {
+			picked = append(picked, strategy.Pick(nil, candidates))
+		}
+
+		return picked
+	}
+
+	/// When
+	race, raceErr := ParseDispatchStrategy(DispatchRace, 0)
+	_, unknownErr := ParseDispatchStrategy("fastest", 0)
+
+	/// Then
+	if !reflect.DeepEqual(picks(1), picks(1)) {
+		t.Errorf("Should have picked the same with the same seed")
+	}
+
+	if race != nil || raceErr != nil {
+		t.Errorf("Should not have a strategy for racing, got %v", raceErr)
+	}
+
+	if unknownErr == nil {
+		t.Errorf("Should have rejected an unknown strategy")
+	}
+}
+
+func Test_DispatchingWithEachStrategy_ShouldBurnAllOnce(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{
+		DispatchLeastLoaded,
+		DispatchRandom,
+		DispatchRoundRobin,
+		DispatchShortestFinish,
+		DispatchWeighted,
+	} {
+		name := name
+
+		t.Run(name, func(t *testing.T) {
+			/// Setup
+			t.Parallel()
+			clock := NewFakeClock(time.Unix(0, 0))
+			suite := NewDefaultTestSuite()
+			suite.clock = clock
+			suite.dispatch, _ = ParseDispatchStrategy(name, 1)
+			players := suite.SetUpSystem()
+			defer players.Terminate()
+
+			/// When
+			report, err := players.WaitAdvancing(clock, suite.waitDuration)
+			discreteSuite := NewDefaultTestSuite()
+			discreteSuite.dispatch, _ = ParseDispatchStrategy(name, 1)
+			discrete := RunDiscreteSimulation(discreteSuite.DiscreteParams(1))
+
+			/// Then
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, report := range []Report{report, discrete} {
+				if !report.Completed || report.BurnedCount != players.BookCount() {
+					t.Errorf("Should have burned %d, got %d", players.BookCount(), report.BurnedCount)
+				}
+
+				for id, count := range report.BurnedIDs {
+					if count != 1 {
+						t.Errorf("Should have burned %s once, but burned %d times", id, count)
+					}
+				}
+
+				if len(report.IncineratorContrib) != players.IncineratorCount() {
+					t.Errorf("Every incinerator should have burned, got %v", report.IncineratorContrib)
+				}
+			}
+
+			t.Logf(
+				"Incinerators burned %v concurrently and %v discretely",
+				players.incineratorGroup.IncineratorContribMap(),
+				discrete.IncineratorContrib,
+			)
+		})
+	}
+}
+
+func Test_ChangingIncineratorsWhileDispatching_ShouldStillBurnAllOnce(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	clock := NewFakeClock(time.Unix(0, 0))
+	suite := NewDefaultTestSuite()
+	suite.clock = clock
+	suite.dispatch = NewRoundRobinDispatch()
+	players := suite.SetUpSystem()
+	defer players.Terminate()
+	ig := players.incineratorGroup
+	removed := players.incinerators[1]
+
+	added := NewIncinerator(suite.ctx, &IncineratorParams{
+		Capacity:    suite.incineratorCap,
+		Clock:       clock,
+		ID:          "added",
+		Logger:      suite.logger,
+		MinCapacity: suite.incineratorMinCap,
+	})
+
+	/// When
+	for ix := 0; ix < 100; ix++ {
+		runtime.Gosched()
+		clock.AdvanceToNext()
+	}
+
+	errs := []error{ig.Remove("0"), ig.Remove(removed.UID()), ig.Add(added)}
+	report, err := players.WaitAdvancing(clock, suite.waitDuration)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("Should have changed membership, got %v", err)
+		}
+	}
+
+	if !report.Completed || len(report.Violations) > 0 {
+		t.Errorf("Should have burned all once, got %v", report.Violations)
+	}
+
+	if report.IncineratorContrib["added"] == 0 {
+		t.Errorf("Added incinerator should have burned something")
+	}
+
+	select {
+	case <-removed.Retire():
+	case <-time.After(suite.waitDuration):
+		t.Errorf("Removed incinerator should have retired")
+	}
+}
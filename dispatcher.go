@@ -0,0 +1,275 @@
+package goburnbooks
+
+import (
+	"context"
+	"sync"
+)
+
+// A dispatchMember is an incinerator as seen by a dispatcher, which hands it
+// batches through a channel of its own.
+type dispatchMember struct {
+	batchCh     chan []Burnable
+	incinerator FIncinerator
+	removed     bool
+}
+
+// A dispatcher stands between a provider and the incinerators of a group, so
+// that a DispatchStrategy rather than the scheduler decides which of the ready
+// incinerators receives each batch. Every incinerator consumes from its own
+// dispatchedProvider, whose ready signals all go to the dispatcher.
+//
+// A batch is routed by category: the picked incinerator only receives the part
+// of the batch that it accepts, and the rest is held for the next one that is
+// ready to accept some of it. Burnables that no incinerator accepts go along
+// with any part, to be rejected.
+type dispatcher struct {
+	changedCh chan interface{}
+	members   map[string]*dispatchMember
+	mutex     sync.Mutex
+	provider  BurnableProvider
+	readyCh   chan string
+	strategy  DispatchStrategy
+}
+
+// Get a provider for an incinerator to consume from.
+func (d *dispatcher) add(incinerator FIncinerator) BurnableProvider {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	member := &dispatchMember{
+		batchCh:     make(chan []Burnable),
+		incinerator: incinerator,
+	}
+
+	d.members[incinerator.UID()] = member
+
+	return &dispatchedProvider{
+		batchCh:  member.batchCh,
+		provider: d.provider,
+		readyCh:  d.readyCh,
+	}
+}
+
+// Stop dispatching to an incinerator. Only call this once the incinerator is
+// retiring, since it is then handed an empty batch for every ready signal the
+// dispatcher has received from it, in order to let it go. A batch that has
+// been asked for by then may still go to it.
+func (d *dispatcher) remove(id string) {
+	d.mutex.Lock()
+
+	if member, ok := d.members[id]; ok {
+		member.removed = true
+	}
+
+	d.mutex.Unlock()
+
+	select {
+	case d.changedCh <- true:
+	default:
+	}
+}
+
+func (d *dispatcher) member(id string) *dispatchMember {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.members[id]
+}
+
+func (d *dispatcher) isRemoved(member *dispatchMember) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return member.removed
+}
+
+// Check whether a Burnable can go to an incinerator, because it accepts the
+// Burnable or no incinerator that is still around does.
+func (d *dispatcher) routable(member *dispatchMember, burnable Burnable) bool {
+	if member.incinerator.Accepts(burnable) {
+		return true
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, other := range d.members {
+		if !other.removed && other.incinerator.Accepts(burnable) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Split a batch into the part that can go to an incinerator and the rest.
+func (d *dispatcher) split(member *dispatchMember, batch []Burnable) ([]Burnable, []Burnable) {
+	part := make([]Burnable, 0, len(batch))
+	rest := make([]Burnable, 0)
+
+	for _, burnable := range batch {
+		if d.routable(member, burnable) {
+			part = append(part, burnable)
+		} else {
+			rest = append(rest, burnable)
+		}
+	}
+
+	return part, rest
+}
+
+func (d *dispatcher) loop(ctx context.Context) {
+	ready := make([]*dispatchMember, 0)
+	var batch []Burnable
+	var holding bool
+	var part []Burnable
+	var picked *dispatchMember
+	var provideCh <-chan []Burnable
+	var provideReadyCh chan<- string
+	var readyID string
+	var rest []Burnable
+	var sendCh chan<- []Burnable
+
+	release := func(member *dispatchMember) bool {
+		select {
+		case member.batchCh <- []Burnable{}:
+			return true
+
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// The sequence of operation here is:
+	// - Once an incinerator is ready, signal ready to the provider on its
+	// behalf, unless a batch has been asked for or is being held already.
+	// - Once the batch arrives, let the strategy pick one of the incinerators
+	// that are ready by then and can take some of it, and hand that part over.
+	// - Keep holding the rest until it has all been handed over, then ask for
+	// another batch if there are still incinerators ready.
+	//
+	// Removed incinerators are let go whenever the sequence is refreshed, unless
+	// a batch has been asked for or is being held, which may still go to them so
+	// that it is not stranded.
+	refresh := func() bool {
+		kept := make([]*dispatchMember, 0, len(ready))
+
+		for _, member := range ready {
+			if provideCh == nil && !holding && d.isRemoved(member) {
+				if !release(member) {
+					return false
+				}
+
+				continue
+			}
+
+			kept = append(kept, member)
+		}
+
+		ready = kept
+
+		switch {
+		case len(ready) == 0:
+			provideReadyCh = nil
+
+		case holding && picked == nil:
+			routable := make([]*dispatchMember, 0, len(ready))
+			candidates := make([]IncineratorLoad, 0, len(ready))
+
+			for _, member := range ready {
+				if part, _ := d.split(member, batch); len(part) > 0 || len(batch) == 0 {
+					routable = append(routable, member)
+					candidates = append(candidates, member.incinerator.Load())
+				}
+			}
+
+			if len(routable) == 0 {
+				break
+			}
+
+			picked = routable[d.strategy.Pick(batch, candidates)]
+			part, rest = d.split(picked, batch)
+			sendCh = picked.batchCh
+
+		case !holding && provideCh == nil:
+			provideReadyCh = d.provider.ReceiveProvideReadyChannel()
+			readyID = ready[0].incinerator.UID()
+		}
+
+		return true
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-d.changedCh:
+
+		case id := <-d.readyCh:
+			if member := d.member(id); member != nil {
+				ready = append(ready, member)
+			}
+
+		case provideReadyCh <- readyID:
+			provideReadyCh = nil
+			provideCh = d.provider.SendBurnablesChannel()
+
+		case batch = <-provideCh:
+			provideCh = nil
+			holding = true
+
+		case sendCh <- part:
+			kept := make([]*dispatchMember, 0, len(ready))
+
+			for _, member := range ready {
+				if member != picked {
+					kept = append(kept, member)
+				}
+			}
+
+			ready = kept
+			batch = rest
+			holding = len(rest) > 0
+			part = nil
+			picked = nil
+			rest = nil
+			sendCh = nil
+		}
+
+		if !refresh() {
+			return
+		}
+	}
+}
+
+func newDispatcher(provider BurnableProvider, strategy DispatchStrategy) *dispatcher {
+	return &dispatcher{
+		changedCh: make(chan interface{}, 1),
+		members:   make(map[string]*dispatchMember, 0),
+		provider:  provider,
+		readyCh:   make(chan string),
+		strategy:  strategy,
+	}
+}
+
+// A dispatchedProvider looks like the provider to an incinerator, except that
+// batches come from the dispatcher.
+type dispatchedProvider struct {
+	batchCh  chan []Burnable
+	provider BurnableProvider
+	readyCh  chan string
+}
+
+// The provider itself is terminated by whoever owns it.
+func (dp *dispatchedProvider) Terminate() {}
+
+func (dp *dispatchedProvider) BurnableProviderID() string {
+	return dp.provider.BurnableProviderID()
+}
+
+func (dp *dispatchedProvider) ReceiveProvideReadyChannel() chan<- string {
+	return dp.readyCh
+}
+
+func (dp *dispatchedProvider) SendBurnablesChannel() <-chan []Burnable {
+	return dp.batchCh
+}
@@ -0,0 +1,80 @@
+package goburnbooks
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_DrainingSystemMidway_ShouldNotLoseOrDoubleBurnBooks(t *testing.T) {
+	t.Parallel()
+	verifyDrainingMidway(NewDefaultTestSuite(), t)
+}
+
+func Test_DrainingDispatchedSystemMidway_ShouldNotLoseOrDoubleBurnBooks(t *testing.T) {
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	suite.dispatch = NewRoundRobinDispatch()
+	verifyDrainingMidway(suite, t)
+}
+
+func verifyDrainingMidway(suite *TestSuite, t *testing.T) {
+	/// Setup
+	players := suite.SetUpSystem()
+	defer players.Terminate()
+	time.Sleep(suite.tripDelay * 3)
+
+	/// When
+	<-players.supplyPileGroup.Drain()
+	remainingMap := players.supplyPileGroup.Remaining()
+
+	// The gophers that are still carrying books deliver them before the
+	// incinerators are drained.
+	for _, gopher := range players.gophers {
+		<-gopher.Drain()
+	}
+
+	<-players.incineratorGroup.Drain()
+
+	/// Then
+	select {
+	case <-players.supplyPileGroup.Drain():
+	case <-time.After(suite.waitDuration):
+		t.Errorf("Should have returned the same closed channel when draining again")
+	}
+
+	takenMap := players.supplyPileGroup.SupplyPileContribMap()
+	burnedIDMap := players.incineratorGroup.BurnedIDMap()
+	totalTaken := totalContribCount(takenMap)
+	totalRemaining := totalContribCount(remainingMap)
+
+	if totalTaken+totalRemaining != players.BookCount() {
+		t.Errorf(
+			"Taken %d and remaining %d should add up to %d",
+			totalTaken,
+			totalRemaining,
+			players.BookCount(),
+		)
+	}
+
+	if totalRemaining == 0 {
+		t.Errorf("Should have drained before all books were taken")
+	}
+
+	if len(burnedIDMap) != totalTaken {
+		t.Errorf("Should have burned %d, but got %d", totalTaken, len(burnedIDMap))
+	}
+
+	for key, value := range burnedIDMap {
+		if value != 1 {
+			t.Errorf("%s should have been burned once, but got %d", key, value)
+		}
+	}
+
+	for _, pile := range players.supplyPiles {
+		id := pile.SupplyPileID()
+
+		if remainingMap[id] != pile.Remaining() {
+			t.Errorf("Pile %s should have %d left, got %d", id, pile.Remaining(), remainingMap[id])
+		}
+	}
+}
@@ -1,86 +1,280 @@
 package goburnbooks
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 )
 
 // Gopher represents a worker in the system.
+//
+// Draining a gopher drains its taker, so that it stops taking from supply
+// piles, and lets it deliver whatever it carries. The returned channel is
+// closed once it carries nothing and no incinerator waits for it anymore.
 type Gopher interface {
 	BurnableProvider
 	SupplyTaker
 }
 
 // GopherParams represents all the required parameters to build a Gopher.
+//
+// A gopher with a speed, in units of distance per second, computes every trip
+// from the location of the pile it took from to that of the incinerator it is
+// headed for, as long as the latter is listed among the destinations or is
+// part of the same simulation, which fills in where its incinerators are for
+// every gopher that does not already know. Since it
+// needs to know where it is headed, it only sets out once an incinerator has
+// signalled ready, which then waits for the gopher to arrive. Behind a
+// dispatch strategy, this is the incinerator the dispatcher signalled ready on
+// behalf of, which may not be the one that ends up with the batch. Trips take
+// the trip duration if the gopher has no speed or the destination is unknown.
+//
+// A gopher that makes return trips walks back to the pile after every batch,
+// taking as long as it took to get to the incinerator, before taking again.
 type GopherParams struct {
 	BurnableProviderRawParams
 	SupplyTakerRawParams
+	Clock        Clock
+	Destinations map[string]Location
 	Logger       Logger
+	Metrics      Metrics
+	ReturnTrip   bool
+	Speed        float64
 	TripDuration time.Duration
 }
 
 type gopher struct {
+	*lifecycle
 	BurnableProvider
 	SupplyTaker
 	GopherParams
-	receiveSupplyCh chan []Suppliable
-	sendBurnableCh  chan []Burnable
+	deliverOnce          sync.Once
+	deliveredCh          chan interface{}
+	log                  LeveledLogger
+	receiveDestinationCh chan string
+	receiveLoadCh        chan SupplyLoad
+	sendBurnableCh       chan []Burnable
 }
 
 func (g *gopher) String() string {
 	return fmt.Sprintf("Gopher %s", g.BPID)
 }
 
+func (g *gopher) Terminate() {
+	g.lifecycle.Terminate()
+}
+
+// The taker is drained first, so that any load it holds reaches the gopher, and
+// the provider last, once the gopher has handed over everything it carries.
+func (g *gopher) Drain() <-chan interface{} {
+	g.deliverOnce.Do(func() {
+		go func() {
+			<-g.SupplyTaker.Drain()
+			<-g.drain()
+			<-g.BurnableProvider.(Drainer).Drain()
+			close(g.deliveredCh)
+		}()
+	})
+
+	return g.deliveredCh
+}
+
+// Get how long it takes to get from a pile to an incinerator.
+func (gp *GopherParams) tripDuration(origin Location, incineratorID string) time.Duration {
+	destination, ok := gp.Destinations[incineratorID]
+
+	if !ok {
+		return gp.TripDuration
+	}
+
+	return travelDuration(origin.Distance(destination), gp.Speed, gp.TripDuration)
+}
+
+// Learn where the incinerators that are not among the destinations yet are.
+// This must happen before the gopher is supplied or consumed, i.e. before it
+// looks up any destination.
+func (g *gopher) learnDestinations(destinations map[string]Location) {
+	g.Destinations = withDestinations(g.Destinations, destinations)
+}
+
+// Get the known destinations along with the specified ones, without touching
+// either map. Those already known win.
+func withDestinations(known map[string]Location, others map[string]Location) map[string]Location {
+	merged := make(map[string]Location, len(known)+len(others))
+
+	for id, location := range others {
+		merged[id] = location
+	}
+
+	for id, location := range known {
+		merged[id] = location
+	}
+
+	return merged
+}
+
+// Wait out a trip, which is cut short if the gopher stops.
+func (g *gopher) travel(duration time.Duration) bool {
+	select {
+	case <-g.Clock.After(duration):
+		return true
+
+	case <-g.ctx.Done():
+		return false
+	}
+}
+
 func (g *gopher) loopWork() {
-	logger := g.Logger
-	receiveSupplyCh := g.receiveSupplyCh
+	logger := g.log
+	receiveLoadCh := g.receiveLoadCh
+	drainingCh := g.drainingCh
+	var draining bool
+	var arrivedAt time.Time
 	var burnables []Burnable
+	var origin Location
+	var readyWait time.Duration
+	var receiveDestinationCh chan string
 	var sendBurnableCh chan []Burnable
+	var tripDuration time.Duration
 
 	for {
 		// Note that the logic in the gopher is quite simple. This is because the
 		// heavy lifting has been delegated to the taker and provider. As a result
 		// the gopher is only responsible for transfering resources from the receive
 		// channel to the send channel and simulating travel time.
+		//
+		// A gopher without a destination channel travels first and waits for an
+		// incinerator to be ready afterwards, while one with a destination channel
+		// does the opposite.
 		select {
-		case supplies := <-receiveSupplyCh:
-			logger.Printf("%v received %d supplies", g, len(supplies))
-			receiveSupplyCh = nil
-			burnables = ExtractBurnablesFromSuppliables(supplies...)
+		case <-g.ctx.Done():
+			return
+
+		case load := <-receiveLoadCh:
+			logger.Debug(
+				"received supplies",
+				Field(FieldBookCount, len(load.Supplies)),
+				Field(FieldPeerID, load.PileID),
+			)
+
+			receiveLoadCh = nil
+			burnables = ExtractBurnablesFromSuppliables(load.Supplies...)
+			origin = load.Origin
+			departedAt := g.Clock.Now()
+
+			if g.receiveDestinationCh != nil {
+				arrivedAt = departedAt
+				receiveDestinationCh = g.receiveDestinationCh
+				break
+			}
+
+			tripDuration = g.TripDuration
+
+			if !g.travel(tripDuration) {
+				return
+			}
+
+			arrivedAt = g.Clock.Now()
+			sendBurnableCh = g.sendBurnableCh
+			g.Metrics.ObserveTrip(g.BPID, arrivedAt.Sub(departedAt))
+
+		case incID := <-receiveDestinationCh:
+			receiveDestinationCh = nil
+			tripDuration = g.tripDuration(origin, incID)
+			departedAt := g.Clock.Now()
+			readyWait = departedAt.Sub(arrivedAt)
+			logger.Debug("heading for incinerator", Field(FieldPeerID, incID))
+
+			if !g.travel(tripDuration) {
+				return
+			}
+
 			sendBurnableCh = g.sendBurnableCh
-			time.Sleep(g.TripDuration)
+			g.Metrics.ObserveTrip(g.BPID, g.Clock.Now().Sub(departedAt))
 
 		case sendBurnableCh <- burnables:
+			if g.receiveDestinationCh == nil {
+				readyWait = g.Clock.Now().Sub(arrivedAt)
+			}
+
+			g.Metrics.ObserveBatch(g.BPID, len(burnables), readyWait)
 			sendBurnableCh = nil
 			burnables = nil
-			receiveSupplyCh = g.receiveSupplyCh
+
+			if g.ReturnTrip && !g.travel(tripDuration) {
+				return
+			}
+
+			receiveLoadCh = g.receiveLoadCh
+
+		case <-drainingCh:
+			drainingCh = nil
+			draining = true
+		}
+
+		// A gopher that would receive a load again carries nothing.
+		if draining && receiveLoadCh != nil {
+			logger.Info("drained")
+			return
 		}
 	}
 }
 
-// NewGopher returns a new Gopher.
-func NewGopher(params *GopherParams) Gopher {
+// NewGopher returns a new Gopher. Cancelling the context stops the gopher, as
+// well as the taker and provider it is made of.
+//
+// The gopher travels on the real clock if none is specified, and its taker
+// shares its clock unless the latter has its own.
+func NewGopher(ctx context.Context, params *GopherParams) Gopher {
 	bpRawParams := params.BurnableProviderRawParams
 	stRawParams := params.SupplyTakerRawParams
-	receiveSupplyCh := make(chan []Suppliable)
+	clock := clockOrDefault(params.Clock)
+
+	if stRawParams.Clock == nil {
+		stRawParams.Clock = clock
+	}
+
+	var receiveDestinationCh chan string
+	receiveLoadCh := make(chan SupplyLoad)
 	sendBurnablesCh := make(chan []Burnable)
 
+	if params.Speed > 0 {
+		receiveDestinationCh = make(chan string)
+	}
+
+	provider := NewBurnableProvider(ctx, &BurnableProviderParams{
+		BurnableProviderRawParams: bpRawParams,
+		BPLogger:                  params.Logger,
+		ReceiveBurnableSourceCh:   sendBurnablesCh,
+		SendDestinationCh:         receiveDestinationCh,
+	})
+
+	taker := NewSupplyTaker(ctx, &SupplyTakerParams{
+		SendLoadDestCh:       receiveLoadCh,
+		SupplyTakerRawParams: stRawParams,
+		STLogger:             params.Logger,
+	})
+
+	// The taker and provider are only terminated once the gopher itself has
+	// stopped relaying between them.
 	gp := &gopher{
-		BurnableProvider: NewBurnableProvider(&BurnableProviderParams{
-			BurnableProviderRawParams: bpRawParams,
-			BPLogger:                  params.Logger,
-			ReceiveBurnableSourceCh:   sendBurnablesCh,
-		}),
-		SupplyTaker: NewSupplyTaker(&SupplyTakerParams{
-			SendSupplyDestCh:     receiveSupplyCh,
-			SupplyTakerRawParams: stRawParams,
-			STLogger:             params.Logger,
+		lifecycle: newLifecycle(ctx, nil, func() {
+			provider.Terminate()
+			taker.Terminate()
 		}),
-		GopherParams:    *params,
-		receiveSupplyCh: receiveSupplyCh,
-		sendBurnableCh:  sendBurnablesCh,
+		BurnableProvider:     provider,
+		SupplyTaker:          taker,
+		GopherParams:         *params,
+		deliveredCh:          make(chan interface{}),
+		receiveDestinationCh: receiveDestinationCh,
+		receiveLoadCh:        receiveLoadCh,
+		sendBurnableCh:       sendBurnablesCh,
 	}
 
-	go gp.loopWork()
+	gp.Clock = clock
+	gp.Metrics = metricsOrDefault(gp.Metrics)
+	gp.log = Leveled(gp.Logger).With(Field(FieldActor, "gopher"), Field(FieldActorID, gp.BPID))
+	gp.spawn(gp.loopWork)
 	return gp
 }
@@ -1,6 +1,8 @@
 package goburnbooks
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 )
@@ -12,7 +14,12 @@ func Test_GopherDeliveringBurnables_ShouldBurnAll(t *testing.T) {
 
 	/// When
 	players := suite.SetUpSystem()
-	time.Sleep(suite.integrationWaitDuration)
+	defer players.Terminate()
+	report, err := players.Wait(suite.integrationWaitDuration)
+
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	/// Then
 	pileGroup := players.supplyPileGroup
@@ -29,6 +36,15 @@ func Test_GopherDeliveringBurnables_ShouldBurnAll(t *testing.T) {
 		t.Errorf("Should have %d books, but got %d", totalSupplyCount, totalBookCount)
 	}
 
+	if !report.Completed || report.BurnedCount != totalBookCount {
+		t.Errorf(
+			"Should have completed with %d burned, got %d (completed: %t)",
+			totalBookCount,
+			report.BurnedCount,
+			report.Completed,
+		)
+	}
+
 	allBurned := incineratorGroup.Burned()
 	allBurnedLen := len(allBurned)
 	burnedIDMap := incineratorGroup.BurnedIDMap()
@@ -80,3 +96,130 @@ func Test_GopherDeliveringBurnables_ShouldBurnAll(t *testing.T) {
 		}
 	}
 }
+
+func Test_GopherWithSpeed_ShouldTravelBetweenLocations(t *testing.T) {
+	t.Parallel()
+
+	// The incinerator is 5 away, and each book burns for a second. Without a
+	// return trip, the gopher takes the second book as soon as it has delivered
+	// the first, but can only set out once the incinerator is ready again.
+	for returnTrip, expected := range map[bool]time.Duration{
+		false: 12 * time.Second,
+		true:  16 * time.Second,
+	} {
+		returnTrip := returnTrip
+		expected := expected
+
+		t.Run(fmt.Sprintf("returnTrip=%t", returnTrip), func(t *testing.T) {
+			/// Setup
+			t.Parallel()
+			ctx := context.Background()
+			clock := NewFakeClock(time.Unix(0, 0))
+			burnDuration := ScenarioDuration(time.Second)
+			timeout := ScenarioDuration(time.Millisecond)
+
+			scenario := &Scenario{
+				Gophers: []GopherSpec{{
+					Capacity:     1,
+					ID:           "0",
+					ReturnTrip:   returnTrip,
+					Speed:        1,
+					TakeTimeout:  timeout,
+					TripDuration: ScenarioDuration(time.Hour),
+				}},
+				Incinerators: []IncineratorSpec{{
+					Capacity: 1,
+					ID:       "0",
+					Location: &Location{X: 3, Y: 4},
+				}},
+				SupplyPiles: []SupplyPileSpec{{
+					Books: []BookSpec{
+						{BurnDuration: burnDuration, ID: "a"},
+						{BurnDuration: burnDuration, ID: "b"},
+					},
+					ID:          "0",
+					TakeTimeout: timeout,
+				}},
+			}
+
+			simulation := NewSimulation(ctx, scenario.SimulationParams(ctx, &ScenarioParams{Clock: clock}))
+			defer simulation.Terminate()
+			players := &TestPlayers{simulation: simulation}
+
+			/// When
+			report, err := players.WaitAdvancing(clock, time.Duration(5e9))
+			discrete := RunDiscreteSimulation(scenario.DiscreteParams())
+
+			/// Then
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !report.Completed || !discrete.Completed {
+				t.Fatalf("Should have completed")
+			}
+
+			if discrete.Duration != expected {
+				t.Errorf("Should have taken %v discretely, got %v", expected, discrete.Duration)
+			}
+
+			if report.Duration < expected || report.Duration > expected+time.Second {
+				t.Errorf("Should have taken about %v concurrently, got %v", expected, report.Duration)
+			}
+		})
+	}
+}
+
+func Test_GopherWithoutDestinations_ShouldTravelToIncineratorLocation(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	ctx := context.Background()
+	clock := NewFakeClock(time.Unix(0, 0))
+	timeout := time.Millisecond
+
+	pile := NewSupplyPile(ctx, &SupplyPileParams{
+		Clock:       clock,
+		ID:          "0",
+		Supply:      []Suppliable{NewBook(&BookParams{BurnDuration: time.Second, Clock: clock, ID: "a"})},
+		TakeTimeout: timeout,
+	})
+
+	gopher := NewGopher(ctx, &GopherParams{
+		BurnableProviderRawParams: BurnableProviderRawParams{BPID: "0"},
+		SupplyTakerRawParams:      SupplyTakerRawParams{Cap: 1, STID: "0", TakeTimeout: timeout},
+		Clock:                     clock,
+		Speed:                     1,
+		TripDuration:              time.Hour,
+	})
+
+	incinerator := NewIncinerator(ctx, &IncineratorParams{
+		Capacity: 1,
+		Clock:    clock,
+		ID:       "0",
+		Location: Location{X: 3, Y: 4},
+	})
+
+	simulation := NewSimulation(ctx, &SimulationParams{
+		Clock:        clock,
+		Gophers:      []Gopher{gopher},
+		Incinerators: []FIncinerator{incinerator},
+		SupplyPiles:  []FSupplyPile{pile},
+	})
+
+	defer simulation.Terminate()
+	players := &TestPlayers{simulation: simulation}
+
+	/// When
+	report, err := players.WaitAdvancing(clock, time.Duration(5e9))
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The incinerator is 5 away, and the book burns for a second. Had the gopher
+	// not known where the incinerator is, the trip would have taken an hour.
+	if expected := 6 * time.Second; report.Duration < expected || report.Duration > expected+time.Second {
+		t.Errorf("Should have taken about %v, got %v", expected, report.Duration)
+	}
+}
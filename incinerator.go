@@ -1,12 +1,18 @@
 package goburnbooks
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Incinerator represents something that can burn a Burnable.
 type Incinerator interface {
+	Terminator
+
+	// This channel is closed once the incinerator has terminated.
 	BurnResultChannel() <-chan BurnResult
 	Consume(provider BurnableProvider)
 	UID() string
@@ -15,25 +21,124 @@ type Incinerator interface {
 // FIncinerator represents an incinerator that has all functionalities.
 type FIncinerator interface {
 	Incinerator
+
+	// Retiring stops the incinerator from signalling ready to its providers,
+	// while Burnables already received are burned to completion. It honours
+	// ready signals that have already been received by burning the batch that
+	// follows each of them, so that no provider is left hanging. The returned
+	// channel is closed once every burn has completed and every result has been
+	// emitted, after which the incinerator can be terminated.
+	Retire() <-chan interface{}
+
+	// Draining is the same as retiring.
+	Drainer
+
+	// Get how busy the incinerator is right now.
+	Load() IncineratorLoad
+
+	// Check whether the incinerator accepts the categories of a Burnable.
+	Accepts(burnable Burnable) bool
+}
+
+// A stealer is an incinerator whose Burnables that are still waiting to burn
+// may be stolen by idle siblings, and which may steal them in turn. Since a
+// waiting Burnable is either granted or stolen under the same lock, it is never
+// burned twice.
+type stealer interface {
+	FIncinerator
+
+	// Get the weight that the incinerator may steal, which is 0 unless nothing
+	// is queued in it, its scheduler has capacity to spare and it still takes on
+	// work.
+	idle() uint
+
+	// Hand waiting Burnables of up to some weight over to a thief, and return
+	// the weight that was stolen.
+	steal(thief stealer, weight uint) uint
+
+	// Queue a Burnable while it is being stolen, so that it takes up capacity
+	// right away, even before it is handed over.
+	reserve(burnable Burnable) *burnTicket
+
+	// Burn a Burnable that was stolen with its reserved ticket, and return false
+	// if the incinerator no longer takes on work, in which case the victim keeps
+	// it.
+	burnStolen(ticket *burnTicket, params BurnResultParams) bool
+
+	// Get notified whenever Burnables are queued or done burning.
+	watch(changedCh chan<- interface{})
 }
 
 // IncineratorParams represents the required parameters to set up an incinerator.
+//
+// Capacities are measured in weight, where Burnables that are not Weighted
+// weigh 1.
 type IncineratorParams struct {
 	Capacity uint
+	Clock    Clock
 	Logger   Logger
 	ID       string
+	Metrics  Metrics
+
+	// This is where gophers with a speed travel to. A simulation lets its
+	// gophers know, unless they have it among their destinations already.
+	Location Location
 
 	// This represents the minimum capacity required before this incinerator can
-	// signal availability.
+	// signal availability, i.e. it signals ready again once the weight of a
+	// batch that is left to burn falls below it.
 	MinCapacity uint
+
+	// FallibleBurnables that still fail after all retries are buried here, if
+	// it is set.
+	DeadLetterPile DeadLetterPile
+	RetryPolicy    RetryPolicy
+
+	// Burnables that are Prioritized are burned highest priority first, across
+	// the batches of every provider. To keep those of low priority from
+	// starving, a queued Burnable gains one priority level for every aging
+	// interval it waits, unless the interval is 0. Among Burnables of equal
+	// priority, those that are Deadlined are burned earliest deadline first.
+	PriorityAging time.Duration
+
+	// The categories that are accepted, or all of them if there are none, and
+	// those that are not, which take precedence. Burnables that are not
+	// accepted fail with ErrNotAccepted without burning.
+	Accepts []Category
+	Rejects []Category
+}
+
+func (ip *IncineratorParams) accepts(value interface{}) bool {
+	return acceptsCategories(value, ip.Accepts, ip.Rejects)
 }
 
 // The consume ready channel is here to coordinate access to the incinerator
 // by allowing only one provider to provide burnables at any time. Thus, it has
 // a buffer of 1.
 type incinerator struct {
+	*lifecycle
 	IncineratorParams
-	burnResultCh chan BurnResult
+	batchSequence uint64
+	burnResultCh  chan BurnResult
+	log           LeveledLogger
+	retiredCh     chan interface{}
+	retireOnce    sync.Once
+	retiringCh    chan interface{}
+	sequence      uint64
+
+	// Every provider, as well as stolen Burnables, share this scheduler, so that
+	// the weight in flight stays within the capacity.
+	scheduler *burnScheduler
+
+	// These let idle siblings in a group steal Burnables that are still waiting
+	// to burn.
+	stealMutex sync.Mutex
+	watchers   []chan<- interface{}
+
+	// These are updated atomically to keep track of the load.
+	burningWeight   int64
+	pendingDuration int64
+	queuedWeight    int64
 }
 
 func (i *incinerator) String() string {
@@ -44,16 +149,50 @@ func (i *incinerator) BurnResultChannel() <-chan BurnResult {
 	return i.burnResultCh
 }
 
+func (i *incinerator) Drain() <-chan interface{} {
+	return i.Retire()
+}
+
+func (i *incinerator) Retire() <-chan interface{} {
+	i.retireOnce.Do(func() {
+		i.seal()
+		close(i.retiringCh)
+
+		go func() {
+			i.wait()
+			close(i.retiredCh)
+		}()
+	})
+
+	return i.retiredCh
+}
+
+func (i *incinerator) Accepts(burnable Burnable) bool {
+	return i.accepts(burnable)
+}
+
+func (i *incinerator) Load() IncineratorLoad {
+	return IncineratorLoad{
+		Burning:         uint(atomic.LoadInt64(&i.burningWeight)),
+		Capacity:        i.Capacity,
+		ID:              i.ID,
+		PendingDuration: time.Duration(atomic.LoadInt64(&i.pendingDuration)),
+		Queued:          uint(atomic.LoadInt64(&i.queuedWeight)),
+	}
+}
+
 func (i *incinerator) Consume(provider BurnableProvider) {
-	go func() {
-		capacity := i.Capacity
+	i.spawn(func() {
 		burnResult := i.burnResultCh
-		burning := make(chan interface{}, capacity)
-		logger := i.Logger
+		scheduler := i.scheduler
+		ctx := i.ctx
 		providerID := provider.BurnableProviderID()
 		provideReadyCh := provider.ReceiveProvideReadyChannel()
 		resetSequenceCh := make(chan interface{}, 1)
+		retiringCh := i.retiringCh
 		var provideCh <-chan []Burnable
+		var retiring bool
+		logger := i.log.With(Field(FieldPeerID, providerID))
 
 		// Initialize this channel every time a new batch of Burnables is received.
 		// Emissions from this channel means that enough items from a batch have
@@ -70,15 +209,51 @@ func (i *incinerator) Consume(provider BurnableProvider) {
 
 		for {
 			select {
+			case <-ctx.Done():
+				return
+
+			// A provider that has received a ready signal will hand over its next
+			// batch to no one else, so wait for that batch before leaving. Burns
+			// that have already started are tracked separately, so the loop can
+			// stop without cutting them short.
+			case <-retiringCh:
+				if provideCh == nil {
+					logger.Info("retiring, no longer consuming")
+					return
+				}
+
+				logger.Info("retiring after the pending batch")
+				retiringCh = nil
+				retiring = true
+
 			case provideReadyCh <- i.ID:
-				logger.Printf("%v is ready to consume from %v", i, provider)
+				logger.Debug("ready to consume")
 				provideReadyCh = nil
 				provideCh = provider.SendBurnablesChannel()
 
 			case burnables := <-provideCh:
 				// Nullify the provide channel to let the sequence run in peace.
-				logger.Printf("%v received %d from %v", i, len(burnables), provider)
 				provideCh = nil
+				batchID := fmt.Sprintf("%s-%d", i.ID, atomic.AddUint64(&i.batchSequence, 1))
+
+				// The batch ID is that of the pile the batch was supplied from, so that
+				// both ends of the batch can be matched up. The incinerator numbers its
+				// own batches for the burn results.
+				logger.Debug(
+					"received batch",
+					Field(FieldBatchID, supplyBatchIDs(burnables)),
+					Field(FieldBookCount, len(burnables)),
+					Field("burnBatchID", batchID),
+				)
+				receivedAt := i.Clock.Now()
+
+				burnables = i.reject(ctx, burnables, BurnResultParams{
+					BatchID:       batchID,
+					IncineratorID: i.ID,
+					ProviderID:    providerID,
+					StartTime:     receivedAt,
+				})
+
 				batchCount := uint(len(burnables))
 				enoughProcessedCh = make(chan interface{}, 1)
 
@@ -87,62 +262,96 @@ func (i *incinerator) Consume(provider BurnableProvider) {
 					break
 				}
 
-				addProcessed := make(chan interface{})
-				processedCount := uint(0)
+				// This channel has enough buffer for the entire batch, so that burns
+				// never block while reporting the weight they are done with.
+				processedCh := make(chan uint, batchCount)
+				batchWeight := totalWeight(burnables...)
 
-				var mutex sync.RWMutex
+				i.fork(func() {
+					processedWeight := uint(0)
 
-				accessAddProcessed := func() chan interface{} {
-					mutex.RLock()
-					defer mutex.RUnlock()
-					return addProcessed
-				}
+					for processedCount := uint(1); processedCount <= batchCount; processedCount++ {
+						select {
+						case weight := <-processedCh:
+							processedWeight += weight
 
-				nullifyAddProcessed := func() {
-					mutex.Lock()
-					defer mutex.Unlock()
-					addProcessed = nil
-				}
+						case <-ctx.Done():
+							return
+						}
 
-				go func() {
-					for {
-						select {
-						case <-accessAddProcessed():
-							processedCount++
-
-							// Once we have processed enough items in a batch, send a signal
-							// via the appropriate channel so that we can signal ready and
-							// reinitialize the provide channel in order to receive the next
-							// batch.
-							if batchCount-processedCount < i.MinCapacity {
-								enoughProcessedCh <- true
-								nullifyAddProcessed()
-							}
+						// Once we have processed enough weight in a batch, send a signal
+						// via the appropriate channel so that we can signal ready and
+						// reinitialize the provide channel in order to receive the next
+						// batch. The last item always counts, in case the min capacity
+						// is 0.
+						if batchWeight-processedWeight < i.MinCapacity ||
+							processedCount == batchCount {
+							enoughProcessedCh <- true
+							return
 						}
 					}
-				}()
-
-				for _, burnable := range burnables {
-					go func(burnable Burnable) {
-						// Since this channel has a limited buffer, once the capacity is
-						// reached this will block.
-						burning <- true
-						burnable.Burn()
-						<-burning
-
-						go func() {
-							if addProcessed := accessAddProcessed(); addProcessed != nil {
-								addProcessed <- true
+				})
+
+				tickets := scheduler.enqueue(burnables...)
+
+				for ix, burnable := range burnables {
+					burnable := burnable
+					ticket := tickets[ix]
+					expected := int64(expectedBurnDuration(burnable))
+					weight := int64(ticket.weight)
+					atomic.AddInt64(&i.queuedWeight, weight)
+					atomic.AddInt64(&i.pendingDuration, expected)
+
+					params := BurnResultParams{
+						BatchID:       batchID,
+						Burned:        burnable,
+						IncineratorID: i.ID,
+						ProviderID:    providerID,
+						Sequence:      atomic.AddUint64(&i.sequence, 1),
+						StartTime:     receivedAt,
+					}
+
+					i.fork(func() {
+						// Once the capacity is reached, this blocks until the scheduler
+						// picks this Burnable. If a sibling steals it in the meantime, it
+						// is handed over, or queued again if the sibling no longer takes
+						// on work.
+						for !scheduler.acquire(ctx, ticket) {
+							if ticket.thief == nil {
+								return
 							}
-						}()
 
-						result := NewBurnResult(burnable, i.ID, providerID)
-						burnResult <- result
-					}(burnable)
+							stolen := params
+							stolen.StolenFrom = i.ID
+
+							if ticket.thief.burnStolen(ticket.handover, stolen) {
+								atomic.AddInt64(&i.queuedWeight, -weight)
+								atomic.AddInt64(&i.pendingDuration, -expected)
+								processedCh <- ticket.weight
+								return
+							}
+
+							ticket = scheduler.enqueue(burnable)[0]
+						}
+
+						result := i.burnGranted(ctx, scheduler, ticket, params)
+						processedCh <- ticket.weight
+
+						select {
+						case burnResult <- result:
+						case <-ctx.Done():
+						}
+					})
 				}
 
+				i.changed()
+
 			case <-enoughProcessedCh:
-				logger.Printf("%v has burned enough, signalling ready to %v", i, provider)
+				if retiring {
+					return
+				}
+
+				logger.Debug("burned enough, signalling ready")
 				enoughProcessedCh = nil
 				resetSequenceCh <- true
 
@@ -150,7 +359,188 @@ func (i *incinerator) Consume(provider BurnableProvider) {
 				provideReadyCh = provider.ReceiveProvideReadyChannel()
 			}
 		}
-	}()
+	})
+}
+
+// Burn a Burnable once its ticket has been granted, keeping track of the load,
+// and complete its result params with the outcome.
+func (i *incinerator) burnGranted(
+	ctx context.Context,
+	scheduler *burnScheduler,
+	ticket *burnTicket,
+	params BurnResultParams,
+) BurnResult {
+	expected := int64(expectedBurnDuration(params.Burned))
+	weight := int64(ticket.weight)
+	atomic.AddInt64(&i.queuedWeight, -weight)
+	atomic.AddInt64(&i.burningWeight, weight)
+	i.Metrics.AddBurning(i.ID, 1)
+	startedAt := i.Clock.Now()
+	attempts, err, buryErr := i.burn(ctx, params.Burned)
+	endedAt := i.Clock.Now()
+	i.Metrics.ObserveBurn(i.ID, endedAt.Sub(startedAt), err)
+	scheduler.release(ticket.weight)
+	atomic.AddInt64(&i.burningWeight, -weight)
+	atomic.AddInt64(&i.pendingDuration, -expected)
+	i.Metrics.AddBurning(i.ID, -1)
+	i.changed()
+	params.Attempts = attempts
+	params.BuryErr = buryErr
+	params.EndTime = endedAt
+	params.Err = err
+	params.QueueWait = startedAt.Sub(params.StartTime)
+	return NewBurnResult(&params)
+}
+
+// Notify whoever watches the incinerator that its load has changed, without
+// waiting for them.
+func (i *incinerator) changed() {
+	i.stealMutex.Lock()
+	defer i.stealMutex.Unlock()
+
+	for _, changedCh := range i.watchers {
+		select {
+		case changedCh <- true:
+		default:
+		}
+	}
+}
+
+func (i *incinerator) watch(changedCh chan<- interface{}) {
+	i.stealMutex.Lock()
+	defer i.stealMutex.Unlock()
+	i.watchers = append(i.watchers, changedCh)
+}
+
+func (i *incinerator) idle() uint {
+	if i.isSealed() || i.Load().Queued > 0 {
+		return 0
+	}
+
+	return i.scheduler.spare()
+}
+
+func (i *incinerator) steal(thief stealer, weight uint) uint {
+	stolen := i.scheduler.steal(thief, weight)
+
+	if stolen > 0 {
+		i.log.Debug("stolen from", Field(FieldPeerID, thief.UID()), Field(FieldBookCount, stolen))
+	}
+
+	return stolen
+}
+
+// Stolen Burnables wait for the same capacity as those of the providers, but
+// they cannot be stolen again.
+func (i *incinerator) reserve(burnable Burnable) *burnTicket {
+	ticket := i.scheduler.enqueueStolen(burnable)
+	atomic.AddInt64(&i.queuedWeight, int64(ticket.weight))
+	atomic.AddInt64(&i.pendingDuration, int64(expectedBurnDuration(burnable)))
+	return ticket
+}
+
+func (i *incinerator) burnStolen(ticket *burnTicket, params BurnResultParams) bool {
+	params.IncineratorID = i.ID
+
+	spawned := i.spawn(func() {
+		if !i.scheduler.acquire(i.ctx, ticket) {
+			return
+		}
+
+		result := i.burnGranted(i.ctx, i.scheduler, ticket, params)
+
+		select {
+		case i.burnResultCh <- result:
+		case <-i.ctx.Done():
+		}
+	})
+
+	// The reserved ticket is given up, since the victim keeps the Burnable.
+	if !spawned {
+		i.scheduler.withdraw(ticket)
+		atomic.AddInt64(&i.queuedWeight, -int64(ticket.weight))
+		atomic.AddInt64(&i.pendingDuration, -int64(expectedBurnDuration(params.Burned)))
+	}
+
+	return spawned
+}
+
+// Emit a failed result for every Burnable of a batch that the incinerator does
+// not accept, and return those that it does.
+func (i *incinerator) reject(
+	ctx context.Context,
+	burnables []Burnable,
+	params BurnResultParams,
+) []Burnable {
+	accepted := make([]Burnable, 0, len(burnables))
+
+	for _, burnable := range burnables {
+		if i.Accepts(burnable) {
+			accepted = append(accepted, burnable)
+			continue
+		}
+
+		i.log.Warn("does not accept, rejecting", Field(FieldBookID, burnable.BurnableID()))
+		rejected := params
+		rejected.Burned = burnable
+		rejected.EndTime = params.StartTime
+		rejected.Err = ErrNotAccepted
+		rejected.Sequence = atomic.AddUint64(&i.sequence, 1)
+
+		i.fork(func() {
+			select {
+			case i.burnResultCh <- NewBurnResult(&rejected):
+			case <-ctx.Done():
+			}
+		})
+	}
+
+	return accepted
+}
+
+// Burn a Burnable, retrying if it is fallible and fails. Failures that are
+// given up on are buried in the dead letter pile, and the last error returned
+// is why that did not work, if it did not.
+func (i *incinerator) burn(ctx context.Context, burnable Burnable) (uint, error, error) {
+	fallible, ok := burnable.(FallibleBurnable)
+
+	if !ok {
+		burnable.Burn()
+		return 1, nil, nil
+	}
+
+	logger := i.log.With(Field(FieldBookID, burnable.BurnableID()))
+	maxAttempts := i.RetryPolicy.attemptCount()
+	attempts := uint(0)
+	var err error
+
+	for attempts < maxAttempts {
+		attempts++
+
+		if err = fallible.TryBurn(ctx); err == nil || ctx.Err() != nil {
+			return attempts, err, nil
+		}
+
+		logger.Warn("failed to burn", Field("attempt", attempts), Field("error", err))
+
+		if attempts < maxAttempts {
+			select {
+			case <-i.Clock.After(i.RetryPolicy.backoffAfter(attempts)):
+			case <-ctx.Done():
+				return attempts, ctx.Err(), nil
+			}
+		}
+	}
+
+	var buryErr error
+
+	if pile := i.DeadLetterPile; pile != nil {
+		if buryErr = pile.Bury(burnable); buryErr != nil {
+			logger.Error("could not bury", Field("error", buryErr))
+		}
+	}
+
+	return attempts, err, buryErr
 }
 
 func (i *incinerator) UID() string {
@@ -160,12 +550,26 @@ func (i *incinerator) UID() string {
 // NewIncinerator creates a new incinerator with a specified pending channel
 // and capacity. The capacity determines how many Burnables can be burned at any
 // given point in time.
-func NewIncinerator(params *IncineratorParams) FIncinerator {
+//
+// Cancelling the context stops the incinerator from consuming, and closes the
+// burn result channel once all ongoing burns have completed. Retries are backed
+// off on the real clock if none is specified.
+func NewIncinerator(ctx context.Context, params *IncineratorParams) FIncinerator {
+	burnResultCh := make(chan BurnResult)
+
 	i := &incinerator{
+		lifecycle:         newLifecycle(ctx, nil, func() { close(burnResultCh) }),
 		IncineratorParams: *params,
-		burnResultCh:      make(chan BurnResult),
+		burnResultCh:      burnResultCh,
+		retiredCh:         make(chan interface{}),
+		retiringCh:        make(chan interface{}),
 	}
 
+	i.Clock = clockOrDefault(i.Clock)
+	i.Metrics = metricsOrDefault(i.Metrics)
+	i.scheduler = newBurnScheduler(i.Capacity, i.PriorityAging, i.Clock)
+	i.log = Leveled(i.Logger).With(Field(FieldActor, "incinerator"), Field(FieldActorID, i.ID))
+
 	if i.Capacity < i.MinCapacity {
 		panic(fmt.Sprintf(
 			"%v has capacity %d less than min capacity %d",
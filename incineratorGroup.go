@@ -1,17 +1,58 @@
 package goburnbooks
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
 )
 
-// IncineratorGroup represents a group of incinerators.
+var (
+	// ErrDuplicateIncinerator is returned when adding an incinerator to a group
+	// that already has one with the same UID.
+	ErrDuplicateIncinerator = errors.New("incinerator is already in the group")
+
+	// ErrGroupStopped is returned when adding a member to a group that is
+	// draining or has terminated.
+	ErrGroupStopped = errors.New("group has stopped")
+
+	// ErrRacingCategories is returned when adding an incinerator that accepts
+	// only some categories to a group whose incinerators race for every batch,
+	// since it would reject what its siblings may accept.
+	ErrRacingCategories = errors.New("incinerator accepts only some categories, but the group races")
+
+	// ErrUnknownIncinerator is returned when removing an incinerator that is not
+	// in the group.
+	ErrUnknownIncinerator = errors.New("incinerator is not in the group")
+)
+
+// IncineratorGroup represents a group of incinerators, whose membership may
+// change while it is running.
 type IncineratorGroup interface {
 	Incinerator
+
+	// Add an incinerator, which starts consuming from every provider that the
+	// group consumes from.
+	Add(incinerator FIncinerator) error
+
+	// Remove an incinerator by its UID. It retires right away, so that it stops
+	// signalling ready while its ongoing burns complete and are recorded, and it
+	// is terminated afterwards.
+	Remove(id string) error
+
+	// Draining drains and then terminates every incinerator in the group. The
+	// returned channel is closed once all their burn results have been recorded.
+	// Since a draining incinerator waits for the batch that a provider owes it,
+	// the providers should be drained first.
+	Drainer
 	Burned() []BurnResult
 
-	// Get the id's of all burned Burnables.
+	// Get the id's of all burned Burnables, excluding those that failed.
 	BurnedIDMap() map[string]int
 
+	// Get the id's of all Burnables that failed to burn.
+	FailedIDMap() map[string]int
+
 	// Get the contributions (i.e. burn count) of each incinerator.
 	IncineratorContribMap() map[string]int
 
@@ -20,17 +61,138 @@ type IncineratorGroup interface {
 }
 
 // IncineratorGroupParams represents all the required parameters to build an
-// IncineratorGroup.
+// IncineratorGroup. If a dispatch strategy is specified, it decides which of
+// the ready incinerators receives each batch from a provider. Otherwise, the
+// incinerators race for every batch, unless some of them accept only some
+// categories. Batches are then routed by category to whichever is ready first.
+//
+// With work stealing, an incinerator that has nothing queued and capacity to
+// spare steals Burnables that still wait to burn in the sibling with the most
+// queued weight, so that a batch is not stuck with a slow incinerator while
+// another sits idle.
 type IncineratorGroupParams struct {
+	Dispatch           DispatchStrategy
 	Incinerators       []FIncinerator
 	BurnResultCapacity uint
+	WorkStealing       bool
 }
 
 type incineratorGroup struct {
+	*lifecycle
 	IncineratorGroupParams
-	mutex        sync.RWMutex
-	burned       []BurnResult
-	burnResultCh chan BurnResult
+	mutex             sync.RWMutex
+	burned            []BurnResult
+	burnResultCh      chan BurnResult
+	drainOnce         sync.Once
+	drainedCh         chan interface{}
+	forwarders        sync.WaitGroup
+	stealCh           chan interface{}
+	updateAllBurnedCh chan BurnResult
+
+	// This mutex guards the membership, which is separate from the results.
+	memberMutex  sync.Mutex
+	dispatchers  []*dispatcher
+	draining     bool
+	incinerators []FIncinerator
+	providers    []BurnableProvider
+}
+
+// Check whether any of some incinerators accepts only some categories.
+func categorized(incinerators ...FIncinerator) bool {
+	for _, i := range incinerators {
+		if i, ok := i.(*incinerator); ok && (len(i.IncineratorParams.Accepts) > 0 || len(i.Rejects) > 0) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the incinerators that are currently in the group.
+func (ig *incineratorGroup) members() []FIncinerator {
+	ig.memberMutex.Lock()
+	defer ig.memberMutex.Unlock()
+	return append([]FIncinerator{}, ig.incinerators...)
+}
+
+func (ig *incineratorGroup) Add(incinerator FIncinerator) error {
+	ig.memberMutex.Lock()
+	defer ig.memberMutex.Unlock()
+	id := incinerator.UID()
+
+	if ig.draining {
+		return ErrGroupStopped
+	}
+
+	if ig.Dispatch == nil && categorized(incinerator) {
+		return fmt.Errorf("%w: %s", ErrRacingCategories, id)
+	}
+
+	for _, i := range ig.incinerators {
+		if i.UID() == id {
+			return fmt.Errorf("%w: %s", ErrDuplicateIncinerator, id)
+		}
+	}
+
+	if !ig.forward(incinerator) {
+		return ErrGroupStopped
+	}
+
+	ig.incinerators = append(ig.incinerators, incinerator)
+	ig.watch(incinerator)
+
+	for _, provider := range ig.providers {
+		go incinerator.Consume(provider)
+	}
+
+	for _, d := range ig.dispatchers {
+		go incinerator.Consume(d.add(incinerator))
+	}
+
+	return nil
+}
+
+func (ig *incineratorGroup) Remove(id string) error {
+	ig.memberMutex.Lock()
+	var removed FIncinerator
+
+	for ix, i := range ig.incinerators {
+		if i.UID() == id {
+			removed = i
+			ig.incinerators = append(ig.incinerators[:ix], ig.incinerators[ix+1:]...)
+			break
+		}
+	}
+
+	dispatchers := ig.dispatchers
+	ig.memberMutex.Unlock()
+
+	if removed == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownIncinerator, id)
+	}
+
+	retiredCh := removed.Retire()
+
+	for _, d := range dispatchers {
+		d.remove(id)
+	}
+
+	// Terminating the incinerator closes its burn result channel, which ends its
+	// forwarding loop once every result has been recorded.
+	spawned := ig.spawn(func() {
+		select {
+		case <-retiredCh:
+		case <-ig.ctx.Done():
+		}
+
+		removed.Terminate()
+	})
+
+	if !spawned {
+		removed.Terminate()
+	}
+
+	return nil
 }
 
 func (ig *incineratorGroup) Burned() []BurnResult {
@@ -47,13 +209,32 @@ func (ig *incineratorGroup) BurnedIDMap() map[string]int {
 	burnedMap := make(map[string]int, 0)
 
 	for _, burned := range allBurned {
-		id := burned.Burned().BurnableID()
-		burnedMap[id] = burnedMap[id] + 1
+		if burned.Err() == nil {
+			id := burned.Burned().BurnableID()
+			burnedMap[id] = burnedMap[id] + 1
+		}
 	}
 
 	return burnedMap
 }
 
+func (ig *incineratorGroup) FailedIDMap() map[string]int {
+	ig.mutex.RLock()
+	allBurned := ig.burned
+	ig.mutex.RUnlock()
+
+	failedMap := make(map[string]int, 0)
+
+	for _, burned := range allBurned {
+		if burned.Err() != nil {
+			id := burned.Burned().BurnableID()
+			failedMap[id] = failedMap[id] + 1
+		}
+	}
+
+	return failedMap
+}
+
 func (ig *incineratorGroup) IncineratorContribMap() map[string]int {
 	ig.mutex.RLock()
 	allBurned := ig.burned
@@ -88,78 +269,239 @@ func (ig *incineratorGroup) BurnResultChannel() <-chan BurnResult {
 	return ig.burnResultCh
 }
 
+// Incinerators added later also consume from the provider.
 func (ig *incineratorGroup) Consume(provider BurnableProvider) {
-	for _, i := range ig.Incinerators {
+	ig.memberMutex.Lock()
+	defer ig.memberMutex.Unlock()
+
+	if ig.Dispatch != nil {
+		d := newDispatcher(provider, ig.Dispatch)
+
+		if !ig.spawn(func() { d.loop(ig.ctx) }) {
+			return
+		}
+
+		ig.dispatchers = append(ig.dispatchers, d)
+
+		for _, i := range ig.incinerators {
+			go i.Consume(d.add(i))
+		}
+
+		return
+	}
+
+	ig.providers = append(ig.providers, provider)
+
+	for _, i := range ig.incinerators {
 		go i.Consume(provider)
 	}
 }
 
+func (ig *incineratorGroup) Drain() <-chan interface{} {
+	ig.drainOnce.Do(func() {
+		ig.memberMutex.Lock()
+		ig.draining = true
+		dispatchers := ig.dispatchers
+		ig.memberMutex.Unlock()
+		incinerators := ig.members()
+		drainedChs := make([]<-chan interface{}, len(incinerators))
+
+		for ix, i := range incinerators {
+			drainedChs[ix] = i.Drain()
+		}
+
+		// The dispatchers let go of the draining incinerators, once they have
+		// handed over any batch that they have asked for on their behalf.
+		for _, d := range dispatchers {
+			for _, i := range incinerators {
+				d.remove(i.UID())
+			}
+		}
+
+		go func() {
+			for _, drainedCh := range drainedChs {
+				<-drainedCh
+			}
+
+			// Terminating drained incinerators closes their burn result channels,
+			// which in turn ends the forwarding loops below once every result has
+			// been recorded. Removed incinerators are terminated on their own.
+			for _, i := range incinerators {
+				i.Terminate()
+			}
+
+			ig.forwarders.Wait()
+			close(ig.drainedCh)
+		}()
+	})
+
+	return ig.drainedCh
+}
+
 func (ig *incineratorGroup) UID() string {
 	var id string
 
-	for _, i := range ig.Incinerators {
+	for _, i := range ig.members() {
 		id += id + "-" + i.UID()
 	}
 
 	return id
 }
 
-// Loop each incinerator to fetch burned updates.
-func (ig *incineratorGroup) loopBurn() {
-	updateAllBurnedCh := make(chan BurnResult)
-
-	for _, i := range ig.Incinerators {
-		go func(i FIncinerator) {
-			resetSequenceCh := make(chan interface{}, 1)
-			var burnResultCh = i.BurnResultChannel()
-			var burnResult BurnResult
-			var updateBurnedCh chan<- BurnResult
-
-			for {
-				// The sequence of this statement is:
-				// - When we receive a new burn result, set the burn result channel to
-				// nil to process in peace. Afterwards, initialize the burn result and
-				// result update channel.
-				// - After the burn result has been updated, reinstate the burn result
-				// channel to keep receiving updates.
-				select {
-				case burned, ok := <-burnResultCh:
-					burnResultCh = nil
-
-					if ok {
-						burnResult = burned
-						updateBurnedCh = updateAllBurnedCh
-					} else {
-						return
-					}
-
-				case updateBurnedCh <- burnResult:
-					burnResult = nil
-					updateBurnedCh = nil
-					resetSequenceCh <- true
-
-				case <-resetSequenceCh:
-					burnResultCh = i.BurnResultChannel()
+// Loop an incinerator to fetch its burned updates until its burn result channel
+// is closed. Only call this while holding the member mutex, so that no
+// forwarder is added once the group has started draining.
+func (ig *incineratorGroup) forward(i FIncinerator) bool {
+	updateAllBurnedCh := ig.updateAllBurnedCh
+	ig.forwarders.Add(1)
+
+	spawned := ig.spawn(func() {
+		defer ig.forwarders.Done()
+		resetSequenceCh := make(chan interface{}, 1)
+		var burnResultCh = i.BurnResultChannel()
+		var burnResult BurnResult
+		var updateBurnedCh chan<- BurnResult
+
+		for {
+			// The sequence of this statement is:
+			// - When we receive a new burn result, set the burn result channel to
+			// nil to process in peace. Afterwards, initialize the burn result and
+			// result update channel.
+			// - After the burn result has been updated, reinstate the burn result
+			// channel to keep receiving updates.
+			select {
+			case <-ig.ctx.Done():
+				return
+
+			case burned, ok := <-burnResultCh:
+				burnResultCh = nil
+
+				if ok {
+					// Note that this mutex is only used to modify the burned result
+					// map, since said map is accessible via a getter method.
+					ig.mutex.Lock()
+					ig.burned = append(ig.burned, burned)
+					ig.mutex.Unlock()
+					burnResult = burned
+					updateBurnedCh = updateAllBurnedCh
+				} else {
+					return
 				}
+
+			case updateBurnedCh <- burnResult:
+				burnResult = nil
+				updateBurnedCh = nil
+				resetSequenceCh <- true
+
+			case <-resetSequenceCh:
+				burnResultCh = i.BurnResultChannel()
 			}
-		}(i)
+		}
+	})
+
+	if !spawned {
+		ig.forwarders.Done()
+	}
+
+	return spawned
+}
+
+// Watch an incinerator for changes in its load, if work is stolen. Since it may
+// be idle already, it gets the chance to steal right away.
+func (ig *incineratorGroup) watch(i FIncinerator) {
+	s, ok := i.(stealer)
+
+	if !ok || !ig.WorkStealing {
+		return
 	}
 
-	go func() {
+	s.watch(ig.stealCh)
+
+	select {
+	case ig.stealCh <- true:
+	default:
+	}
+}
+
+// Steal work for idle incinerators whenever the load of any of them changes.
+func (ig *incineratorGroup) loopSteal() {
+	ig.spawn(func() {
+		for {
+			select {
+			case <-ig.ctx.Done():
+				return
+
+			case <-ig.stealCh:
+				ig.stealWork()
+			}
+		}
+	})
+}
+
+// Let every idle incinerator steal from the sibling with the most queued
+// weight, until it has no capacity left or there is nothing it can steal.
+func (ig *incineratorGroup) stealWork() {
+	stealers := make([]stealer, 0)
+	queued := make(map[string]uint, 0)
+
+	for _, i := range ig.members() {
+		if s, ok := i.(stealer); ok {
+			stealers = append(stealers, s)
+			queued[s.UID()] = s.Load().Queued
+		}
+	}
+
+	for _, thief := range stealers {
+		for weight := thief.idle(); weight > 0; {
+			var victim stealer
+
+			for _, s := range stealers {
+				if s != thief && queued[s.UID()] > 0 &&
+					(victim == nil || queued[s.UID()] > queued[victim.UID()]) {
+					victim = s
+				}
+			}
+
+			if victim == nil {
+				break
+			}
+
+			// Anything that the thief does not accept stays queued, so the victim is
+			// only picked again if something else is queued in the meantime.
+			stolen := victim.steal(thief, weight)
+
+			if stolen == 0 || stolen >= queued[victim.UID()] {
+				queued[victim.UID()] = 0
+			} else {
+				queued[victim.UID()] -= stolen
+			}
+
+			if stolen >= weight {
+				break
+			}
+
+			weight -= stolen
+		}
+	}
+}
+
+// Merge the burned updates of every incinerator into the group's burn result
+// channel.
+func (ig *incineratorGroup) loopBurn() {
+	updateAllBurnedCh := ig.updateAllBurnedCh
+
+	ig.spawn(func() {
 		updateBurned := updateAllBurnedCh
 		var burnResultCh chan<- BurnResult
 		var lastBurned BurnResult
 
 		for {
 			select {
+			case <-ig.ctx.Done():
+				return
+
 			case burned := <-updateBurned:
 				updateBurned = nil
-
-				// Note that this mutex is only used to modify the burned result
-				// map, since said map is accessible via a getter method.
-				ig.mutex.Lock()
-				ig.burned = append(ig.burned, burned)
-				ig.mutex.Unlock()
 				lastBurned = burned
 				burnResultCh = ig.burnResultCh
 
@@ -169,20 +511,55 @@ func (ig *incineratorGroup) loopBurn() {
 				updateBurned = updateAllBurnedCh
 			}
 		}
-	}()
+	})
 }
 
 // NewIncineratorGroup creates a new incinerator group from a number of
 // incinerators. An incinerator group implements the same functionalities as
 // an incinerator, so we can access them directly instead of viewing individual
 // incinerators.
-func NewIncineratorGroup(params *IncineratorGroupParams) IncineratorGroup {
-	ig := &incineratorGroup{
-		IncineratorGroupParams: *params,
+//
+// Cancelling the context stops the group as well as all of its incinerators.
+func NewIncineratorGroup(
+	ctx context.Context,
+	params *IncineratorGroupParams,
+) IncineratorGroup {
+	burnResultCh := make(chan BurnResult, params.BurnResultCapacity)
+	var ig *incineratorGroup
+
+	terminateAll := func() {
+		for _, i := range ig.members() {
+			i.Terminate()
+		}
+	}
+
+	groupParams := *params
+
+	if groupParams.Dispatch == nil && categorized(groupParams.Incinerators...) {
+		groupParams.Dispatch = firstReadyDispatch{}
+	}
+
+	ig = &incineratorGroup{
+		lifecycle:              newLifecycle(ctx, terminateAll, func() { close(burnResultCh) }),
+		IncineratorGroupParams: groupParams,
 		burned:                 make([]BurnResult, 0),
-		burnResultCh:           make(chan BurnResult, params.BurnResultCapacity),
+		burnResultCh:           burnResultCh,
+		drainedCh:              make(chan interface{}),
+		incinerators:           append([]FIncinerator{}, params.Incinerators...),
+		stealCh:                make(chan interface{}, 1),
+		updateAllBurnedCh:      make(chan BurnResult),
+	}
+
+	for _, i := range ig.incinerators {
+		ig.forward(i)
+		ig.watch(i)
+	}
+
+	ig.loopBurn()
+
+	if params.WorkStealing {
+		ig.loopSteal()
 	}
 
-	go ig.loopBurn()
 	return ig
 }
@@ -1,6 +1,8 @@
 package goburnbooks
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -28,14 +30,27 @@ func Test_BurnMultiple_ShouldEventuallyBurnAll(t *testing.T) {
 		Incinerators:       incinerators,
 	}
 
-	ig := NewIncineratorGroup(&igParams)
+	ig := NewIncineratorGroup(suite.ctx, &igParams)
+	defer ig.Terminate()
+
+	for _, provider := range providers {
+		defer provider.Terminate()
+	}
 
 	/// When
 	for _, provider := range providers {
 		ig.Consume(provider)
 	}
 
-	time.Sleep(suite.waitDuration)
+	timeoutCh := time.After(suite.waitDuration)
+
+	for received := 0; received < totalBurnCount; received++ {
+		select {
+		case <-ig.BurnResultChannel():
+		case <-timeoutCh:
+			t.Fatalf("Should have burned %d, but got %d", totalBurnCount, received)
+		}
+	}
 
 	// Then
 	contribPercentThreshold := suite.contribPercentThreshold
@@ -65,6 +80,12 @@ func Test_BurnMultiple_ShouldCapAtSpecifiedCapacity(t *testing.T) {
 	t.Parallel()
 
 	suite := NewDefaultTestSuite()
+	ctx, cancel := context.WithCancel(suite.ctx)
+	suite.ctx = ctx
+
+	// Burns in progress cannot be interrupted, so only cancel the context
+	// instead of terminating, which would wait for said burns.
+	defer cancel()
 
 	// Unrealistic burn duration to simulate blocking process.
 	suite.burnDuration = 1e15
@@ -78,21 +99,320 @@ func Test_BurnMultiple_ShouldCapAtSpecifiedCapacity(t *testing.T) {
 		Incinerators:       incinerators,
 	}
 
-	ig := NewIncineratorGroup(&igParams)
+	ig := NewIncineratorGroup(suite.ctx, &igParams)
 
 	/// When
 	for _, provider := range providers {
 		ig.Consume(provider)
 	}
 
-	time.Sleep(suite.waitDuration)
+	// Every incinerator ends up burning as much as it can, with the rest of its
+	// batch queued behind.
+	timeoutCh := time.After(suite.waitDuration)
+
+	for _, incinerator := range incinerators {
+		for load := incinerator.Load(); load.Burning < load.Capacity || load.Queued == 0; load = incinerator.Load() {
+			select {
+			case <-time.After(time.Millisecond):
+			case <-timeoutCh:
+				t.Fatalf("%s should have filled up, got %+v", load.ID, load)
+			}
+		}
+	}
 
 	/// Then
 	contribPercentThreshold := suite.contribPercentThreshold
 	verifyIncGroupFairContrib(ig, contribPercentThreshold, t)
 	burnedLength := len(ig.Burned())
 
+	for _, incinerator := range incinerators {
+		if load := incinerator.Load(); load.Burning > load.Capacity {
+			t.Errorf("%s should have burned at most %d, got %d", load.ID, load.Capacity, load.Burning)
+		}
+	}
+
 	if burnedLength != 0 {
 		t.Errorf("Should not have burned anything, but got %d", burnedLength)
 	}
 }
+
+func Test_BurningFallibleBooks_ShouldRetryAndBuryFailures(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	provideCh := make(chan []Burnable)
+
+	deadLetterPile := NewDeadLetterPile(suite.ctx, &SupplyPileParams{
+		Capacity: 1,
+		ID:       "dead",
+		Logger:   suite.logger,
+	})
+
+	incinerator := NewIncinerator(suite.ctx, &IncineratorParams{
+		Capacity:       suite.incineratorCap,
+		DeadLetterPile: deadLetterPile,
+		ID:             "0",
+		Logger:         suite.logger,
+		RetryPolicy:    RetryPolicy{MaxAttempts: 3, Backoff: suite.burnDuration},
+	})
+
+	provider := NewBurnableProvider(suite.ctx, &BurnableProviderParams{
+		BurnableProviderRawParams: BurnableProviderRawParams{BPID: "0"},
+		BPLogger:                  suite.logger,
+		ReceiveBurnableSourceCh:   provideCh,
+	})
+
+	defer deadLetterPile.Terminate()
+	defer incinerator.Terminate()
+	defer provider.Terminate()
+
+	books := []Burnable{
+		NewBook(&BookParams{BurnDuration: suite.burnDuration, ID: "ok"}),
+		NewBook(&BookParams{BurnDuration: suite.burnDuration, FailCount: 2, ID: "retried"}),
+		NewBook(&BookParams{BurnDuration: suite.burnDuration, FailCount: 3, ID: "failed"}),
+	}
+
+	/// When
+	incinerator.Consume(provider)
+	provideCh <- books
+	results := make(map[string]BurnResult, 0)
+
+	for range books {
+		select {
+		case result := <-incinerator.BurnResultChannel():
+			results[result.Burned().BurnableID()] = result
+
+		case <-time.After(suite.waitDuration):
+			t.Fatalf("Should have burned %d, but got %d", len(books), len(results))
+		}
+	}
+
+	/// Then
+	if result := results["ok"]; result.Err() != nil || result.Attempts() != 1 {
+		t.Errorf("Should have burned at once, got %v", result)
+	}
+
+	if result := results["retried"]; result.Err() != nil || result.Attempts() != 3 {
+		t.Errorf("Should have burned on the last attempt, got %v", result)
+	}
+
+	if result := results["failed"]; result.Err() != ErrBurnFailed || result.Attempts() != 3 {
+		t.Errorf("Should have given up after 3 attempts, got %v", result)
+	}
+
+	if remaining := deadLetterPile.Remaining(); remaining != 1 {
+		t.Errorf("Should have buried 1 book, but got %d", remaining)
+	}
+}
+
+func Test_BurningFallibleBooksIntoFullDeadLetterPile_ShouldReportBuryError(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	provideCh := make(chan []Burnable)
+
+	deadLetterPile := NewDeadLetterPile(suite.ctx, &SupplyPileParams{
+		Capacity: 1,
+		ID:       "dead",
+		Logger:   suite.logger,
+	})
+
+	incinerator := NewIncinerator(suite.ctx, &IncineratorParams{
+		Capacity:       1,
+		DeadLetterPile: deadLetterPile,
+		ID:             "0",
+		Logger:         suite.logger,
+		RetryPolicy:    RetryPolicy{MaxAttempts: 1},
+	})
+
+	provider := NewBurnableProvider(suite.ctx, &BurnableProviderParams{
+		BurnableProviderRawParams: BurnableProviderRawParams{BPID: "0"},
+		BPLogger:                  suite.logger,
+		ReceiveBurnableSourceCh:   provideCh,
+	})
+
+	defer deadLetterPile.Terminate()
+	defer incinerator.Terminate()
+	defer provider.Terminate()
+
+	books := []Burnable{
+		NewBook(&BookParams{FailCount: 1, ID: "buried"}),
+		NewBook(&BookParams{FailCount: 1, ID: "lost"}),
+	}
+
+	/// When
+	incinerator.Consume(provider)
+	provideCh <- books
+	results := make(map[string]BurnResult, 0)
+
+	for range books {
+		select {
+		case result := <-incinerator.BurnResultChannel():
+			results[result.Burned().BurnableID()] = result
+
+		case <-time.After(suite.waitDuration):
+			t.Fatalf("Should have burned %d, but got %d", len(books), len(results))
+		}
+	}
+
+	/// Then
+	if result := results["buried"]; result.Err() != ErrBurnFailed || result.BuryErr() != nil {
+		t.Errorf("Should have buried the first failure, got %v", result)
+	}
+
+	if result := results["lost"]; result.Err() != ErrBurnFailed || result.BuryErr() != ErrPileFull {
+		t.Errorf("Should have failed to bury the second failure, got %v", result)
+	}
+
+	if remaining := deadLetterPile.Remaining(); remaining != 1 {
+		t.Errorf("Should have buried 1 book, but got %d", remaining)
+	}
+}
+
+// A provider whose channels are driven by the test itself.
+type stubProvider struct {
+	burnablesCh chan []Burnable
+	readyCh     chan string
+}
+
+func (sp *stubProvider) Terminate() {}
+
+func (sp *stubProvider) BurnableProviderID() string {
+	return "stub"
+}
+
+func (sp *stubProvider) ReceiveProvideReadyChannel() chan<- string {
+	return sp.readyCh
+}
+
+func (sp *stubProvider) SendBurnablesChannel() <-chan []Burnable {
+	return sp.burnablesCh
+}
+
+func Test_RetiringAfterReadySignal_ShouldBurnPendingBatchThenStop(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	provider := &stubProvider{burnablesCh: make(chan []Burnable), readyCh: make(chan string)}
+
+	incinerator := NewIncinerator(suite.ctx, &IncineratorParams{
+		Capacity: suite.incineratorCap,
+		ID:       "0",
+		Logger:   suite.logger,
+	})
+
+	defer incinerator.Terminate()
+	incinerator.Consume(provider)
+	<-provider.readyCh
+
+	/// When
+	retiredCh := incinerator.Retire()
+
+	books := []Burnable{
+		NewBook(&BookParams{BurnDuration: suite.burnDuration, ID: "0"}),
+		NewBook(&BookParams{BurnDuration: suite.burnDuration, ID: "1"}),
+	}
+
+	select {
+	case provider.burnablesCh <- books:
+	case <-time.After(suite.waitDuration):
+		t.Fatalf("Should have accepted the pending batch")
+	}
+
+	for range books {
+		select {
+		case <-incinerator.BurnResultChannel():
+		case <-time.After(suite.waitDuration):
+			t.Fatalf("Should have burned the pending batch")
+		}
+	}
+
+	/// Then
+	select {
+	case <-retiredCh:
+	case <-time.After(suite.waitDuration):
+		t.Fatalf("Should have retired")
+	}
+
+	select {
+	case id := <-provider.readyCh:
+		t.Errorf("Should not have signalled ready again, got %s", id)
+	default:
+	}
+
+	// Providers that come later are ignored.
+	incinerator.Consume(provider)
+
+	select {
+	case id := <-provider.readyCh:
+		t.Errorf("Should not have consumed after retiring, got %s", id)
+	case <-time.After(suite.burnDuration * 10):
+	}
+}
+
+func Test_ChangingIncineratorsMidRun_ShouldStillBurnAllOnce(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	clock := NewFakeClock(time.Unix(0, 0))
+	suite := NewDefaultTestSuite()
+	suite.clock = clock
+	players := suite.SetUpSystem()
+	defer players.Terminate()
+	ig := players.incineratorGroup
+
+	added := NewIncinerator(suite.ctx, &IncineratorParams{
+		Capacity:    suite.incineratorCap,
+		Clock:       clock,
+		ID:          "added",
+		Logger:      suite.logger,
+		MinCapacity: suite.incineratorMinCap,
+	})
+
+	/// When
+	errs := []error{
+		ig.Remove("0"),
+		ig.Remove("1"),
+		ig.Add(added),
+	}
+
+	report, err := players.WaitAdvancing(clock, suite.waitDuration)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("Should have changed membership, got %v", err)
+		}
+	}
+
+	if !report.Completed || len(report.Violations) > 0 {
+		t.Errorf("Should have burned all once, got %v", report.Violations)
+	}
+
+	if report.IncineratorContrib["added"] == 0 {
+		t.Errorf("Added incinerator should have burned something")
+	}
+
+	if err := ig.Remove("0"); !errors.Is(err, ErrUnknownIncinerator) {
+		t.Errorf("Should not have removed twice, got %v", err)
+	}
+
+	if err := ig.Add(added); !errors.Is(err, ErrDuplicateIncinerator) {
+		t.Errorf("Should not have added twice, got %v", err)
+	}
+
+	for _, gopher := range players.gophers {
+		<-gopher.Drain()
+	}
+
+	<-ig.Drain()
+	late := NewIncinerator(suite.ctx, &IncineratorParams{ID: "late"})
+	defer late.Terminate()
+
+	if err := ig.Add(late); err != ErrGroupStopped {
+		t.Errorf("Should not have added after draining, got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package goburnbooks
+
+import (
+	"math"
+	"time"
+)
+
+// Location represents where a pile or an incinerator is, in whatever unit of
+// distance the gophers' speeds are measured in.
+type Location struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Distance returns the straight-line distance to another Location.
+func (l Location) Distance(other Location) float64 {
+	return math.Hypot(other.X-l.X, other.Y-l.Y)
+}
+
+// Get how long it takes to cover some distance at a speed measured in units of
+// distance per second. Without a speed, every trip takes the fallback.
+func travelDuration(distance float64, speed float64, fallback time.Duration) time.Duration {
+	if speed <= 0 {
+		return fallback
+	}
+
+	return time.Duration(distance / speed * float64(time.Second))
+}
@@ -1,40 +1,317 @@
 package goburnbooks
 
-import "fmt"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
 
 // Logger logs some output.
 type Logger interface {
 	Printf(format string, args ...interface{})
 }
 
-type logger struct {
-	enabled bool
+// LogLevel represents how important a log entry is.
+type LogLevel int
+
+// These are the supported log levels, from the most to the least verbose.
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+
+	case LevelInfo:
+		return "info"
+
+	case LevelWarn:
+		return "warn"
+
+	case LevelError:
+		return "error"
+
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// SlogLevel returns the equivalent log/slog level.
+func (l LogLevel) SlogLevel() slog.Level {
+	switch l {
+	case LevelDebug:
+		return slog.LevelDebug
+
+	case LevelInfo:
+		return slog.LevelInfo
+
+	case LevelWarn:
+		return slog.LevelWarn
+
+	default:
+		return slog.LevelError
+	}
+}
+
+// ParseLogLevel parses a level name such as "debug" or "warn".
+func ParseLogLevel(name string) (LogLevel, error) {
+	for level := LevelDebug; level <= LevelError; level++ {
+		if strings.EqualFold(name, level.String()) {
+			return level, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// These are the keys of the fields that actors attach to their log entries.
+const (
+	FieldActor     = "actor"
+	FieldActorID   = "actorID"
+	FieldBatchID   = "batchID"
+	FieldBookCount = "bookCount"
+	FieldBookID    = "bookID"
+	FieldPeerID    = "peerID"
+)
+
+// LogField represents a key-value pair attached to a log entry.
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// Field creates a new LogField.
+func Field(key string, value interface{}) LogField {
+	return LogField{Key: key, Value: value}
 }
 
-func (l *logger) Printf(format string, args ...interface{}) {
-	if l.enabled {
-		newFormat := l.appendNewLine(format)
-		fmt.Printf(newFormat, args...)
+// LeveledLogger represents a Logger whose entries have a level and structured
+// fields. Printf logs at the debug level, so that a LeveledLogger can be used
+// anywhere a Logger is expected.
+type LeveledLogger interface {
+	Logger
+	Enabled(level LogLevel) bool
+	Log(level LogLevel, msg string, fields ...LogField)
+	Debug(msg string, fields ...LogField)
+	Info(msg string, fields ...LogField)
+	Warn(msg string, fields ...LogField)
+	Error(msg string, fields ...LogField)
+
+	// Get a logger that attaches the specified fields to every entry.
+	With(fields ...LogField) LeveledLogger
+}
+
+// A logSink is where a leveledLogger sends its entries.
+type logSink interface {
+	enabled(level LogLevel) bool
+	write(level LogLevel, msg string, fields []LogField)
+}
+
+type leveledLogger struct {
+	fields []LogField
+	sink   logSink
+}
+
+func (ll *leveledLogger) Printf(format string, args ...interface{}) {
+	if ll.sink.enabled(LevelDebug) {
+		msg := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+		ll.sink.write(LevelDebug, msg, ll.fields)
+	}
+}
+
+func (ll *leveledLogger) Enabled(level LogLevel) bool {
+	return ll.sink.enabled(level)
+}
+
+func (ll *leveledLogger) Log(level LogLevel, msg string, fields ...LogField) {
+	if !ll.sink.enabled(level) {
+		return
 	}
+
+	allFields := make([]LogField, 0, len(ll.fields)+len(fields))
+	allFields = append(allFields, ll.fields...)
+	allFields = append(allFields, fields...)
+	ll.sink.write(level, msg, allFields)
+}
+
+func (ll *leveledLogger) Debug(msg string, fields ...LogField) {
+	ll.Log(LevelDebug, msg, fields...)
+}
+
+func (ll *leveledLogger) Info(msg string, fields ...LogField) {
+	ll.Log(LevelInfo, msg, fields...)
+}
+
+func (ll *leveledLogger) Warn(msg string, fields ...LogField) {
+	ll.Log(LevelWarn, msg, fields...)
+}
+
+func (ll *leveledLogger) Error(msg string, fields ...LogField) {
+	ll.Log(LevelError, msg, fields...)
+}
+
+func (ll *leveledLogger) With(fields ...LogField) LeveledLogger {
+	allFields := make([]LogField, 0, len(ll.fields)+len(fields))
+	allFields = append(allFields, ll.fields...)
+	allFields = append(allFields, fields...)
+	return &leveledLogger{fields: allFields, sink: ll.sink}
 }
 
-func (l *logger) appendNewLine(format string) string {
-	if len(format) > 0 {
-		var newFormat string
+// Format an entry as "level msg key=value ...".
+func formatEntry(level LogLevel, msg string, fields []LogField) string {
+	var builder strings.Builder
+	builder.WriteString(strings.ToUpper(level.String()))
+	builder.WriteString(" ")
+	builder.WriteString(msg)
 
-		if format[len(format)-1] != '\n' {
-			newFormat = format + "\n"
-		} else {
-			newFormat = format
+	for _, field := range fields {
+		fmt.Fprintf(&builder, " %s=%v", field.Key, field.Value)
+	}
+
+	return builder.String()
+}
+
+type discardSink struct{}
+
+func (ds discardSink) enabled(level LogLevel) bool {
+	return false
+}
+
+func (ds discardSink) write(level LogLevel, msg string, fields []LogField) {}
+
+// Send entries to a Printf-based Logger. Since it cannot filter, every level is
+// enabled.
+type printfSink struct {
+	logger Logger
+}
+
+func (ps *printfSink) enabled(level LogLevel) bool {
+	return true
+}
+
+func (ps *printfSink) write(level LogLevel, msg string, fields []LogField) {
+	ps.logger.Printf("%s", formatEntry(level, msg, fields))
+}
+
+// Leveled returns a LeveledLogger for any Logger. A LeveledLogger is returned
+// as is, a nil Logger discards everything, and any other Logger receives every
+// entry through Printf, formatted as text.
+func Leveled(logger Logger) LeveledLogger {
+	switch logger := logger.(type) {
+	case nil:
+		return &leveledLogger{sink: discardSink{}}
+
+	case LeveledLogger:
+		return logger
+
+	default:
+		return &leveledLogger{sink: &printfSink{logger: logger}}
+	}
+}
+
+// Write entries to a writer, one per line. Writes are serialized so that
+// entries from different goroutines do not interleave.
+type writerSink struct {
+	json   bool
+	level  LogLevel
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func (ws *writerSink) enabled(level LogLevel) bool {
+	return level >= ws.level
+}
+
+func (ws *writerSink) write(level LogLevel, msg string, fields []LogField) {
+	var line []byte
+
+	if ws.json {
+		entry := make(map[string]interface{}, len(fields)+3)
+
+		for _, field := range fields {
+			if err, ok := field.Value.(error); ok {
+				entry[field.Key] = err.Error()
+			} else {
+				entry[field.Key] = field.Value
+			}
+		}
+
+		entry["time"] = time.Now().Format(time.RFC3339Nano)
+		entry["level"] = level.String()
+		entry["msg"] = msg
+		encoded, err := json.Marshal(entry)
+
+		if err != nil {
+			encoded, _ = json.Marshal(map[string]string{
+				"level": LevelError.String(),
+				"msg":   fmt.Sprintf("could not encode log entry %q: %v", msg, err),
+			})
 		}
 
-		return newFormat
+		line = append(encoded, '\n')
+	} else {
+		line = []byte(formatEntry(level, msg, fields) + "\n")
+	}
+
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	ws.writer.Write(line)
+}
+
+// NewTextLogger returns a LeveledLogger that writes entries at or above the
+// specified level to a writer as lines of text.
+func NewTextLogger(writer io.Writer, level LogLevel) LeveledLogger {
+	return &leveledLogger{sink: &writerSink{level: level, writer: writer}}
+}
+
+// NewJSONLogger returns a LeveledLogger that writes entries at or above the
+// specified level to a writer as JSON objects, one per line.
+func NewJSONLogger(writer io.Writer, level LogLevel) LeveledLogger {
+	return &leveledLogger{sink: &writerSink{json: true, level: level, writer: writer}}
+}
+
+// Send entries to a log/slog logger.
+type slogSink struct {
+	logger *slog.Logger
+}
+
+func (ss *slogSink) enabled(level LogLevel) bool {
+	return ss.logger.Enabled(context.Background(), level.SlogLevel())
+}
+
+func (ss *slogSink) write(level LogLevel, msg string, fields []LogField) {
+	attrs := make([]slog.Attr, len(fields))
+
+	for ix, field := range fields {
+		attrs[ix] = slog.Any(field.Key, field.Value)
 	}
 
-	return format
+	ss.logger.LogAttrs(context.Background(), level.SlogLevel(), msg, attrs...)
+}
+
+// NewSlogLogger returns a LeveledLogger backed by a log/slog logger, which
+// decides what is enabled and how entries are formatted.
+func NewSlogLogger(logger *slog.Logger) LeveledLogger {
+	return &leveledLogger{sink: &slogSink{logger: logger}}
 }
 
-// NewLogger returns a new Logger.
+// NewLogger returns a new Logger that writes every entry to stdout if enabled,
+// and discards everything otherwise.
 func NewLogger(enabled bool) Logger {
-	return &logger{enabled: enabled}
+	if enabled {
+		return NewTextLogger(os.Stdout, LevelDebug)
+	}
+
+	return Leveled(nil)
 }
@@ -0,0 +1,203 @@
+package goburnbooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type printfRecorder struct {
+	lines []string
+}
+
+func (pr *printfRecorder) Printf(format string, args ...interface{}) {
+	pr.lines = append(pr.lines, fmt.Sprintf(format, args...))
+}
+
+func Test_LoggingBelowLevel_ShouldBeFilteredOut(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	buffer := &bytes.Buffer{}
+	logger := NewTextLogger(buffer, LevelWarn).With(Field(FieldActor, "pile"))
+
+	/// When
+	logger.Debug("debug")
+	logger.Printf("printf %d", 1)
+	logger.Info("info")
+	logger.Warn("warn", Field(FieldBookCount, 2))
+	logger.Error("error")
+
+	/// Then
+	expected := "WARN warn actor=pile bookCount=2\nERROR error actor=pile\n"
+
+	if buffer.String() != expected {
+		t.Errorf("Should have logged %q, got %q", expected, buffer.String())
+	}
+
+	if logger.Enabled(LevelInfo) || !logger.Enabled(LevelError) {
+		t.Errorf("Should only be enabled from warn upwards")
+	}
+}
+
+func Test_LoggingAsJSON_ShouldWriteOneObjectPerEntry(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	buffer := &bytes.Buffer{}
+	logger := NewJSONLogger(buffer, LevelDebug).With(Field(FieldActorID, "1"))
+
+	/// When
+	logger.Info("burned", Field(FieldBookCount, 3), Field("error", errors.New("oops")))
+	logger.Debug("done")
+
+	/// Then
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+
+	if len(lines) != 2 {
+		t.Fatalf("Should have logged 2 lines, got %d", len(lines))
+	}
+
+	var entry map[string]interface{}
+
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry["level"] != "info" ||
+		entry["msg"] != "burned" ||
+		entry[FieldActorID] != "1" ||
+		entry[FieldBookCount] != float64(3) ||
+		entry["error"] != "oops" ||
+		entry["time"] == nil {
+		t.Errorf("Should have logged all fields, got %v", entry)
+	}
+}
+
+func Test_LoggingViaSlog_ShouldFollowHandlerLevel(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	buffer := &bytes.Buffer{}
+
+	handler := slog.NewTextHandler(buffer, &slog.HandlerOptions{
+		Level: LevelInfo.SlogLevel(),
+	})
+
+	logger := NewSlogLogger(slog.New(handler)).With(Field(FieldActor, "gopher"))
+
+	/// When
+	logger.Debug("hidden")
+	logger.Info("shown", Field(FieldBookCount, 4))
+
+	/// Then
+	output := buffer.String()
+
+	if strings.Contains(output, "hidden") {
+		t.Errorf("Should not have logged debug entry, got %q", output)
+	}
+
+	if !strings.Contains(output, "msg=shown actor=gopher bookCount=4") {
+		t.Errorf("Should have logged info entry with fields, got %q", output)
+	}
+}
+
+func Test_LeveledPrintfLogger_ShouldReceiveFormattedEntries(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	recorder := &printfRecorder{}
+
+	/// When
+	logger := Leveled(recorder).With(Field(FieldActor, "incinerator"))
+	logger.Warn("failed", Field(FieldBookID, "0-1"))
+	Leveled(nil).Error("discarded")
+
+	/// Then
+	expected := []string{"WARN failed actor=incinerator bookID=0-1"}
+
+	if fmt.Sprint(recorder.lines) != fmt.Sprint(expected) {
+		t.Errorf("Should have logged %v, got %v", expected, recorder.lines)
+	}
+
+	if Leveled(logger) != logger {
+		t.Errorf("Should have returned leveled logger as is")
+	}
+}
+
+func Test_LoggingBatches_ShouldCorrelateSupplyAndBurn(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	ctx := context.Background()
+	clock := NewFakeClock(time.Unix(0, 0))
+	buffer := &bytes.Buffer{}
+	logger := NewJSONLogger(buffer, LevelDebug)
+	timeout := ScenarioDuration(time.Millisecond)
+	books := make([]BookSpec, 10)
+
+	for ix := range books {
+		books[ix] = BookSpec{BurnDuration: timeout, ID: strconv.Itoa(ix)}
+	}
+
+	scenario := &Scenario{
+		Gophers: []GopherSpec{{
+			Capacity:     3,
+			ID:           "0",
+			TakeTimeout:  timeout,
+			TripDuration: timeout,
+		}},
+		Incinerators: []IncineratorSpec{{Capacity: 3, ID: "0"}},
+		SupplyPiles:  []SupplyPileSpec{{Books: books, ID: "0", TakeTimeout: timeout}},
+	}
+
+	simulation := NewSimulation(ctx, scenario.SimulationParams(ctx, &ScenarioParams{
+		Clock:  clock,
+		Logger: logger,
+	}))
+
+	defer simulation.Terminate()
+	players := &TestPlayers{simulation: simulation}
+
+	/// When
+	if _, err := players.WaitAdvancing(clock, time.Duration(5e9)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every actor must be done logging before the entries are read.
+	simulation.Terminate()
+
+	/// Then
+	supplied := make(map[string]float64, 0)
+	received := make(map[string]float64, 0)
+
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		var entry map[string]interface{}
+
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+
+		batchID, _ := entry[FieldBatchID].(string)
+		bookCount, _ := entry[FieldBookCount].(float64)
+
+		switch {
+		case entry["msg"] == "supplied" && entry[FieldActor] == "pile":
+			supplied[batchID] = bookCount
+
+		case entry["msg"] == "received batch" && entry[FieldActor] == "incinerator":
+			received[batchID] = bookCount
+		}
+	}
+
+	if len(supplied) != 4 {
+		t.Errorf("Should have supplied 4 batches, got %v", supplied)
+	}
+
+	if !reflect.DeepEqual(supplied, received) {
+		t.Errorf("Should have received every batch that was supplied, got %v and %v", supplied, received)
+	}
+}
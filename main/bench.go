@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	gbb "github.com/protoman92/goburnbooks"
+)
+
+type benchResult struct {
+	Gophers      int
+	Incinerators int
+	Report       gbb.Report
+}
+
+// Parse a comma-separated list of positive counts.
+func parseCounts(value string) ([]int, error) {
+	counts := make([]int, 0)
+
+	if value == "" {
+		return counts, nil
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		count, err := strconv.Atoi(strings.TrimSpace(part))
+
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid count %q", part)
+		}
+
+		counts = append(counts, count)
+	}
+
+	return counts, nil
+}
+
+// Copy a scenario with the specified number of gophers and incinerators. Extra
+// actors copy the specs of existing ones in turn.
+func scaleScenario(scenario *gbb.Scenario, gophers int, incinerators int) *gbb.Scenario {
+	scaled := *scenario
+	scaled.Gophers = make([]gbb.GopherSpec, gophers)
+	scaled.Incinerators = make([]gbb.IncineratorSpec, incinerators)
+
+	for ix := range scaled.Gophers {
+		scaled.Gophers[ix] = scenario.Gophers[ix%len(scenario.Gophers)]
+		scaled.Gophers[ix].ID = strconv.Itoa(ix)
+	}
+
+	for ix := range scaled.Incinerators {
+		scaled.Incinerators[ix] = scenario.Incinerators[ix%len(scenario.Incinerators)]
+		scaled.Incinerators[ix].ID = strconv.Itoa(ix)
+	}
+
+	return &scaled
+}
+
+func renderBench(writer io.Writer, format string, results []benchResult) error {
+	if format == formatJSON {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	tw := tabwriter.NewWriter(writer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "GOPHERS\tINCINERATORS\tDURATION\tBURNED\tFAILED\tCOMPLETED")
+
+	for _, result := range results {
+		report := result.Report
+
+		fmt.Fprintf(
+			tw,
+			"%d\t%d\t%v\t%d\t%d\t%t\n",
+			result.Gophers,
+			result.Incinerators,
+			report.Duration,
+			report.BurnedCount,
+			report.FailedCount,
+			report.Completed,
+		)
+	}
+
+	return tw.Flush()
+}
+
+func runBench(args []string, stdout io.Writer, stderr io.Writer) int {
+	opts := &options{}
+	flagSet := newFlagSet("bench", stderr, opts, "deadline", "dispatch", "format", "log", "pile-selection", "scenario", "seed")
+	engine := flagSet.String("engine", engineDiscrete, "Engine to run on: concurrent or discrete")
+	gopherFlag := flagSet.String("gophers", "", "Comma-separated gopher counts (defaults to the scenario's)")
+	incineratorFlag := flagSet.String("incinerators", "", "Comma-separated incinerator counts (defaults to the scenario's)")
+
+	if err := parseFlags(flagSet, opts, args); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	if *engine != engineConcurrent && *engine != engineDiscrete {
+		fmt.Fprintf(stderr, "unknown engine %q\n", *engine)
+		return exitError
+	}
+
+	gopherCounts, err := parseCounts(*gopherFlag)
+
+	if err != nil {
+		fmt.Fprintf(stderr, "gophers: %v\n", err)
+		return exitError
+	}
+
+	incineratorCounts, err := parseCounts(*incineratorFlag)
+
+	if err != nil {
+		fmt.Fprintf(stderr, "incinerators: %v\n", err)
+		return exitError
+	}
+
+	scenario, err := loadScenario(opts)
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	if len(gopherCounts) == 0 {
+		gopherCounts = []int{len(scenario.Gophers)}
+	}
+
+	if len(incineratorCounts) == 0 {
+		incineratorCounts = []int{len(scenario.Incinerators)}
+	}
+
+	logger := newLogger(opts, stderr)
+	results := make([]benchResult, 0)
+	exitCode := exitSuccess
+
+	for _, gophers := range gopherCounts {
+		for _, incinerators := range incineratorCounts {
+			scaled := scaleScenario(scenario, gophers, incinerators)
+			report, timedOut := simulator(opts, logger, nil, scaled, *engine)
+
+			results = append(results, benchResult{
+				Gophers:      gophers,
+				Incinerators: incinerators,
+				Report:       report,
+			})
+
+			if timedOut {
+				fmt.Fprintf(stderr, "%d gophers, %d incinerators: deadline exceeded\n", gophers, incinerators)
+				exitCode = exitTimeout
+				continue
+			}
+
+			for _, violation := range checkInvariants(report) {
+				fmt.Fprintf(stderr, "%d gophers, %d incinerators: %s\n", gophers, incinerators, violation)
+
+				if exitCode == exitSuccess {
+					exitCode = exitViolation
+				}
+			}
+		}
+	}
+
+	if err := renderBench(stdout, opts.format, results); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	return exitCode
+}
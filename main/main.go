@@ -1,175 +1,206 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"math/rand"
-	"strconv"
+	"io"
+	"log/slog"
+	"os"
 	"time"
 
 	gbb "github.com/protoman92/goburnbooks"
 )
 
+// Exit codes, so that scripts can tell a clean run apart from a broken one.
 const (
-	gopherCount        = 10
-	gopherCapacity     = 19
-	gopherTakeTimeout  = time.Duration(1e9)
-	incineratorCap     = 20
-	incineratorMinCap  = incineratorCap / 2
-	incineratorCount   = 6
-	maxBurnDuration    = time.Duration(10e5)
-	minBurnDuration    = time.Duration(1e5)
-	maxTripDelay       = time.Duration(3e5)
-	minTripDelay       = time.Duration(1e5)
-	supplyPerPileCount = 1000
-	supplyPileCount    = 5
-	supplyPileTimeout  = time.Duration(1e9)
-	totalSupplyCount   = supplyPileCount * supplyPerPileCount
+	exitSuccess   = 0
+	exitError     = 1 // Bad usage, or a scenario that cannot be loaded.
+	exitViolation = 2 // The run finished, but an invariant does not hold.
+	exitTimeout   = 3 // The run did not finish before the deadline.
 )
 
-var (
-	logger = gbb.NewLogger(true)
+const (
+	formatJSON = "json"
+	formatText = "text"
+	levelOff   = "off"
 )
 
-func randomDuration(min time.Duration, max time.Duration) time.Duration {
-	return min + time.Duration(rand.Int63n(int64(max-min)))
+// options holds the flags shared by all commands.
+type options struct {
+	deadline     time.Duration
+	dispatch     string
+	format       string
+	logFormat    string
+	logLevel     string
+	pileSelect   string
+	scenarioPath string
+	seed         int64
+	seedSet      bool
+	verbose      bool
 }
 
-func main() {
-	gophers := make([]gbb.Gopher, gopherCount)
-
-	for ix := range gophers {
-		gParams := &gbb.GopherParams{
-			BurnableProviderRawParams: gbb.BurnableProviderRawParams{
-				BPID: strconv.Itoa(ix),
-			},
-			SupplyTakerRawParams: gbb.SupplyTakerRawParams{
-				Cap:         gopherCapacity,
-				STID:        strconv.Itoa(ix),
-				TakeTimeout: gopherTakeTimeout,
-			},
-			Logger:       logger,
-			TripDuration: randomDuration(minTripDelay, maxTripDelay),
-		}
+type command struct {
+	description string
+	run         func(args []string, stdout io.Writer, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"bench": {
+		description: "Run a scenario repeatedly with varying gopher and incinerator counts",
+		run:         runBench,
+	},
+	"report": {
+		description: "Render a report saved by 'run -format json'",
+		run:         runReport,
+	},
+	"run": {
+		description: "Run a scenario and report how it went",
+		run:         runRun,
+	},
+	"validate": {
+		description: "Check a scenario file without running it",
+		run:         runValidate,
+	},
+}
+
+var commandNames = []string{"run", "bench", "validate", "report"}
+
+func usage(stderr io.Writer) {
+	fmt.Fprintln(stderr, "Usage: burnbooks <command> [flags]")
+	fmt.Fprintln(stderr)
+	fmt.Fprintln(stderr, "Commands:")
 
-		gopher := gbb.NewGopher(gParams)
-		gophers[ix] = gopher
+	for _, name := range commandNames {
+		fmt.Fprintf(stderr, "  %-10s %s\n", name, commands[name].description)
 	}
 
-	piles := make([]gbb.FSupplyPile, supplyPileCount)
-	allBooks := make([]gbb.Book, 0)
-	allBookIds := make([]string, 0)
-
-	for ix := range piles {
-		supplies := make([]gbb.Suppliable, supplyPerPileCount)
-
-		for jx := range supplies {
-			id := fmt.Sprintf("%d-%d", ix, jx)
-			duration := randomDuration(minBurnDuration, maxBurnDuration)
-			bParams := &gbb.BookParams{BurnDuration: duration, ID: id}
-			book := gbb.NewBook(bParams)
-			supplies[jx] = book
-			allBooks = append(allBooks, book)
-			allBookIds = append(allBookIds, id)
-		}
+	fmt.Fprintln(stderr)
+	fmt.Fprintln(stderr, "Run 'burnbooks <command> -h' for the flags of a command.")
+}
 
-		pParams := &gbb.SupplyPileParams{
-			Logger:      logger,
-			Supply:      supplies,
-			ID:          strconv.Itoa(ix),
-			TakeTimeout: supplyPileTimeout,
-		}
+// Create a flag set for a command, registering only the shared flags that the
+// command uses.
+func newFlagSet(name string, stderr io.Writer, opts *options, shared ...string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+
+	for _, flagName := range shared {
+		switch flagName {
+		case "deadline":
+			flagSet.DurationVar(&opts.deadline, "deadline", 0, "Stop the run after this long (0 means no deadline)")
+
+		case "dispatch":
+			flagSet.StringVar(&opts.dispatch, "dispatch", "", "How batches are dispatched to incinerators: race, roundRobin, leastLoaded, shortestFinish, weighted or random (overrides the scenario)")
+
+		case "format":
+			flagSet.StringVar(&opts.format, "format", formatText, "Output format: text or json")
+
+		case "log":
+			flagSet.StringVar(&opts.logLevel, "log-level", "warn", "Minimum level to log to stderr: debug, info, warn, error or off")
+			flagSet.StringVar(&opts.logFormat, "log-format", formatText, "Log format: text or json")
+			flagSet.BoolVar(&opts.verbose, "v", false, "Log what every actor does (same as -log-level debug)")
 
-		pile := gbb.NewSupplyPile(pParams)
-		piles[ix] = pile
+		case "pile-selection":
+			flagSet.StringVar(&opts.pileSelect, "pile-selection", "", "How gophers pick piles: race, fullest, roundRobin, nearest, sticky or inOrder (overrides the scenario)")
+
+		case "scenario":
+			flagSet.StringVar(&opts.scenarioPath, "scenario", "", "Path to a JSON scenario file (defaults to the built-in scenario)")
+
+		case "seed":
+			flagSet.Int64Var(&opts.seed, "seed", 0, "Seed for generated books and the discrete engine (overrides the scenario)")
+		}
 	}
 
-	pileGroup := gbb.NewSupplyPileGroup(piles...)
+	return flagSet
+}
 
-	incinerators := make([]gbb.FIncinerator, incineratorCount)
+// Parse the flags for a command and check the shared ones.
+func parseFlags(flagSet *flag.FlagSet, opts *options, args []string) error {
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
 
-	for ix := range incinerators {
-		iParams := &gbb.IncineratorParams{
-			Capacity:    incineratorCap,
-			ID:          strconv.Itoa(ix),
-			Logger:      logger,
-			MinCapacity: incineratorMinCap,
+	flagSet.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			opts.seedSet = true
 		}
+	})
 
-		incinerator := gbb.NewIncinerator(iParams)
-		incinerators[ix] = incinerator
+	if opts.format != "" && opts.format != formatText && opts.format != formatJSON {
+		return fmt.Errorf("unknown format %q", opts.format)
 	}
 
-	igParams := gbb.IncineratorGroupParams{
-		BurnResultCapacity: 0,
-		Incinerators:       incinerators,
+	if opts.deadline < 0 {
+		return fmt.Errorf("deadline must not be negative")
 	}
 
-	incineratorGroup := gbb.NewIncineratorGroup(&igParams)
+	if _, err := gbb.ParseDispatchStrategy(opts.dispatch, 0); err != nil {
+		return err
+	}
 
-	// Start the system
-	for _, gopher := range gophers {
-		go pileGroup.Supply(gopher)
-		go incineratorGroup.Consume(gopher)
+	if _, err := gbb.ParseSelectionStrategy(opts.pileSelect, func(string, string) float64 { return 0 }); err != nil {
+		return err
 	}
 
-	done := make(chan bool, 1)
-	var totalBurnCount int
+	if opts.logFormat != "" && opts.logFormat != formatText && opts.logFormat != formatJSON {
+		return fmt.Errorf("unknown log format %q", opts.logFormat)
+	}
 
-	go func() {
-		burnResultCh := incineratorGroup.BurnResultChannel()
-		var initLastBurned bool
-		var lastBurned gbb.BurnResult
+	if opts.logLevel != "" && opts.logLevel != levelOff {
+		if _, err := gbb.ParseLogLevel(opts.logLevel); err != nil {
+			return err
+		}
+	}
 
-		for {
-			select {
-			case result := <-burnResultCh:
-				fmt.Printf("%v\n", result)
+	return nil
+}
 
-				if initLastBurned && result == lastBurned {
-					panic(fmt.Sprintf("Should not have duplicate result %v", result))
-				}
+// Create the logger described by the options, which writes to stderr so that
+// it does not get mixed up with the output.
+func newLogger(opts *options, stderr io.Writer) gbb.Logger {
+	if opts.verbose {
+		opts.logLevel = gbb.LevelDebug.String()
+	}
 
-				lastBurned = result
+	if opts.logLevel == "" || opts.logLevel == levelOff {
+		return gbb.Leveled(nil)
+	}
 
-				if !initLastBurned {
-					initLastBurned = true
-				}
+	level, _ := gbb.ParseLogLevel(opts.logLevel)
 
-				totalBurnCount++
+	if opts.logFormat == formatJSON {
+		return gbb.NewJSONLogger(stderr, level)
+	}
 
-				if totalBurnCount == totalSupplyCount {
-					done <- true
-				}
-			}
-		}
-	}()
+	handler := slog.NewTextHandler(stderr, &slog.HandlerOptions{
+		Level: level.SlogLevel(),
+	})
 
-	select {
-	case <-done:
-		fmt.Printf("Burned a total of %d books", totalBurnCount)
-		incContrib := incineratorGroup.IncineratorContribMap()
-		providerContrib := incineratorGroup.ProviderContribMap()
-		pileContrib := pileGroup.SupplyPileContribMap()
-		takerContrib := pileGroup.SupplyTakerContribMap()
+	return gbb.NewSlogLogger(slog.New(handler))
+}
 
-		fmt.Println()
-		fmt.Printf("\n>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>\n")
+func runMain(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitError
+	}
 
-		for key, value := range pileContrib {
-			fmt.Printf("Pile %s contributed %d books\n", key, value)
-		}
+	if args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		return exitSuccess
+	}
 
-		fmt.Printf("\n>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>\n")
+	cmd, ok := commands[args[0]]
 
-		for key, value := range incContrib {
-			fmt.Printf("Incinerator %s burned %d book\n", key, value)
-		}
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown command %q\n\n", args[0])
+		usage(stderr)
+		return exitError
+	}
 
-		fmt.Printf("\n>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>\n")
+	return cmd.run(args[1:], stdout, stderr)
+}
 
-		for key, value := range providerContrib {
-			taken := takerContrib[key]
-			fmt.Printf("Gopher %s took %d and delivered %d books\n", key, taken, value)
-		}
-	}
+func main() {
+	os.Exit(runMain(os.Args[1:], os.Stdout, os.Stderr))
 }
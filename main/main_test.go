@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gbb "github.com/protoman92/goburnbooks"
+)
+
+// A scenario whose gopher cannot carry anything.
+const invalidScenario = `{
+  "supplyPiles": [{"id": "0", "books": [{"id": "a"}]}],
+  "gophers": [{"id": "0", "capacity": 0}],
+  "incinerators": [{"id": "0", "capacity": 1}]
+}`
+
+func Test_RunningMain_ShouldExitWithMatchingCode(t *testing.T) {
+	/// Setup
+	invalidPath := filepath.Join(t.TempDir(), "invalid.json")
+
+	if err := os.WriteFile(invalidPath, []byte(invalidScenario), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every valid scenario upholds the invariants, so breaking them takes a
+	// fake run that loses a book.
+	losingSimulator := func(
+		opts *options,
+		logger gbb.Logger,
+		metrics gbb.Metrics,
+		scenario *gbb.Scenario,
+		engine string,
+	) (gbb.Report, bool) {
+		return gbb.Report{BurnedCount: 1, Completed: true, SupplyCount: 2}, false
+	}
+
+	for _, test := range []struct {
+		name      string
+		args      []string
+		simulator func(*options, gbb.Logger, gbb.Metrics, *gbb.Scenario, string) (gbb.Report, bool)
+		expected  int
+	}{
+		{name: "completed", args: []string{"run", "-scenario", "scenarios/small.json", "-log-level", "off"}, expected: exitSuccess},
+		{name: "completedDiscretely", args: []string{"run", "-scenario", "scenarios/small.json", "-engine", "discrete"}, expected: exitSuccess},
+		{name: "unknownCommand", args: []string{"burn"}, expected: exitError},
+		{name: "invalidScenario", args: []string{"run", "-scenario", invalidPath}, expected: exitError},
+		{name: "violation", args: []string{"run", "-scenario", "scenarios/small.json"}, simulator: losingSimulator, expected: exitViolation},
+		{name: "benchViolation", args: []string{"bench", "-scenario", "scenarios/small.json"}, simulator: losingSimulator, expected: exitViolation},
+		{name: "deadline", args: []string{"run", "-deadline", "1ms", "-log-level", "off"}, expected: exitTimeout},
+	} {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			/// Setup
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+
+			if test.simulator != nil {
+				simulator = test.simulator
+				defer func() { simulator = simulate }()
+			}
+
+			/// When
+			code := runMain(test.args, stdout, stderr)
+
+			/// Then
+			if code != test.expected {
+				t.Errorf("Should have exited with %d, got %d: %s", test.expected, code, stderr.String())
+			}
+		})
+	}
+}
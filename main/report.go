@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	gbb "github.com/protoman92/goburnbooks"
+)
+
+const separator = "\n>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>\n"
+
+func sortedKeys(contrib map[string]int) []string {
+	keys := make([]string, 0)
+
+	for key := range contrib {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func renderReport(writer io.Writer, format string, report gbb.Report) error {
+	if format == formatJSON {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	fmt.Fprintf(writer, "Burned a total of %d books", report.BurnedCount)
+
+	if report.FailedCount > 0 {
+		fmt.Fprintf(writer, ", failed to burn %d", report.FailedCount)
+	}
+
+	fmt.Fprintf(writer, " out of %d in %v\n", report.SupplyCount, report.Duration)
+
+	if !report.Completed {
+		fmt.Fprintln(writer, "The run did not complete")
+	}
+
+	fmt.Fprint(writer, separator)
+
+	for _, key := range sortedKeys(report.SupplyPileContrib) {
+		fmt.Fprintf(writer, "Pile %s contributed %d books\n", key, report.SupplyPileContrib[key])
+	}
+
+	fmt.Fprint(writer, separator)
+
+	for _, key := range sortedKeys(report.IncineratorContrib) {
+		fmt.Fprintf(writer, "Incinerator %s burned %d book\n", key, report.IncineratorContrib[key])
+	}
+
+	fmt.Fprint(writer, separator)
+
+	for _, key := range sortedKeys(report.ProviderContrib) {
+		taken := report.SupplyTakerContrib[key]
+		value := report.ProviderContrib[key]
+		fmt.Fprintf(writer, "Gopher %s took %d and delivered %d books\n", key, taken, value)
+	}
+
+	if sla := report.SLA; sla.Deadlined > 0 {
+		fmt.Fprint(writer, separator)
+		fmt.Fprintf(writer, "Missed %d out of %d deadlines\n", sla.Missed, sla.Deadlined)
+
+		for _, key := range sortedKeys(sla.PileMisses) {
+			fmt.Fprintf(writer, "Pile %s missed %d deadlines\n", key, sla.PileMisses[key])
+		}
+
+		for _, key := range sortedKeys(sla.GopherMisses) {
+			fmt.Fprintf(writer, "Gopher %s missed %d deadlines\n", key, sla.GopherMisses[key])
+		}
+
+		for _, key := range sortedKeys(sla.IncineratorMisses) {
+			fmt.Fprintf(writer, "Incinerator %s missed %d deadlines\n", key, sla.IncineratorMisses[key])
+		}
+	}
+
+	return nil
+}
+
+func runReport(args []string, stdout io.Writer, stderr io.Writer) int {
+	opts := &options{}
+	flagSet := newFlagSet("report", stderr, opts, "format")
+
+	flagSet.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: burnbooks report [flags] <report file, or - for stdin>")
+		flagSet.PrintDefaults()
+	}
+
+	if err := parseFlags(flagSet, opts, args); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		return exitError
+	}
+
+	reader := io.Reader(os.Stdin)
+
+	if path := flagSet.Arg(0); path != "-" {
+		file, err := os.Open(path)
+
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+
+		defer file.Close()
+		reader = file
+	}
+
+	var report gbb.Report
+
+	if err := json.NewDecoder(reader).Decode(&report); err != nil {
+		fmt.Fprintf(stderr, "%s: invalid report: %v\n", flagSet.Arg(0), err)
+		return exitError
+	}
+
+	if err := renderReport(stdout, opts.format, report); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	return exitSuccess
+}
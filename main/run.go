@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+
+	gbb "github.com/protoman92/goburnbooks"
+)
+
+const (
+	engineConcurrent = "concurrent"
+	engineDiscrete   = "discrete"
+)
+
+// Run a scenario on the specified engine. The deadline only applies to the
+// concurrent engine, since the discrete engine always runs to completion. So do
+// the metrics, which may be nil.
+func simulate(
+	opts *options,
+	logger gbb.Logger,
+	metrics gbb.Metrics,
+	scenario *gbb.Scenario,
+	engine string,
+) (report gbb.Report, timedOut bool) {
+	if engine == engineDiscrete {
+		return gbb.RunDiscreteSimulation(scenario.DiscreteParams()), false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if opts.deadline > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.deadline)
+		defer cancel()
+	}
+
+	simulation := gbb.NewSimulation(ctx, scenario.SimulationParams(ctx, &gbb.ScenarioParams{
+		Logger:  logger,
+		Metrics: metrics,
+	}))
+
+	report = <-simulation.Done()
+
+	// Wind everything down before returning, so that no burn is cut short.
+	simulation.Terminate()
+	return report, !report.Completed && ctx.Err() == context.DeadlineExceeded
+}
+
+// The function that runs scenarios, so that tests can fake a run.
+var simulator = simulate
+
+// Describe every way in which a report breaks the promise that each book is
+// dealt with exactly once, including whatever the audit found.
+func checkInvariants(report gbb.Report) []string {
+	violations := make([]string, 0)
+
+	if !report.Completed {
+		violations = append(violations, "the run did not complete")
+	} else if processed := report.BurnedCount + report.FailedCount; processed != report.SupplyCount {
+		violations = append(violations, fmt.Sprintf(
+			"processed %d books out of %d",
+			processed,
+			report.SupplyCount,
+		))
+	}
+
+	return append(violations, report.Violations...)
+}
+
+// Serve the metrics at /metrics on the specified address in the background.
+// Closing the returned listener stops the server.
+func serveMetrics(addr string, metrics gbb.PrometheusMetrics) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	go http.Serve(listener, mux)
+	return listener, nil
+}
+
+// Write the final value of every metric to a file.
+func writeMetrics(path string, metrics gbb.PrometheusMetrics) error {
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := metrics.WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func runRun(args []string, stdout io.Writer, stderr io.Writer) int {
+	opts := &options{}
+	flagSet := newFlagSet("run", stderr, opts, "deadline", "dispatch", "format", "log", "pile-selection", "scenario", "seed")
+	engine := flagSet.String("engine", engineConcurrent, "Engine to run on: concurrent or discrete")
+	metricsAddr := flagSet.String("metrics-addr", "", "Serve Prometheus metrics at /metrics on this address during the run")
+	metricsPath := flagSet.String("metrics-file", "", "Write Prometheus metrics to this file once the run is over")
+
+	if err := parseFlags(flagSet, opts, args); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	if *engine != engineConcurrent && *engine != engineDiscrete {
+		fmt.Fprintf(stderr, "unknown engine %q\n", *engine)
+		return exitError
+	}
+
+	scenario, err := loadScenario(opts)
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	var metrics gbb.PrometheusMetrics
+
+	if *metricsAddr != "" || *metricsPath != "" {
+		if *engine != engineConcurrent {
+			fmt.Fprintf(stderr, "metrics are only collected on the %s engine\n", engineConcurrent)
+			return exitError
+		}
+
+		metrics = gbb.NewPrometheusMetrics()
+	}
+
+	if *metricsAddr != "" {
+		listener, err := serveMetrics(*metricsAddr, metrics)
+
+		if err != nil {
+			fmt.Fprintf(stderr, "metrics: %v\n", err)
+			return exitError
+		}
+
+		defer listener.Close()
+	}
+
+	report, timedOut := simulator(opts, newLogger(opts, stderr), metrics, scenario, *engine)
+
+	if *metricsPath != "" {
+		if err := writeMetrics(*metricsPath, metrics); err != nil {
+			fmt.Fprintf(stderr, "metrics: %v\n", err)
+			return exitError
+		}
+	}
+
+	if err := renderReport(stdout, opts.format, report); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	if timedOut {
+		fmt.Fprintf(stderr, "deadline of %v exceeded\n", opts.deadline)
+		return exitTimeout
+	}
+
+	if violations := checkInvariants(report); len(violations) > 0 {
+		for _, violation := range violations {
+			fmt.Fprintln(stderr, violation)
+		}
+
+		return exitViolation
+	}
+
+	return exitSuccess
+}
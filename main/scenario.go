@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	gbb "github.com/protoman92/goburnbooks"
+)
+
+const (
+	gopherCount        = 10
+	gopherCapacity     = 19
+	gopherTakeTimeout  = time.Duration(1e9)
+	incineratorCap     = 20
+	incineratorMinCap  = incineratorCap / 2
+	incineratorCount   = 6
+	maxBurnDuration    = time.Duration(10e5)
+	minBurnDuration    = time.Duration(1e5)
+	maxTripDelay       = time.Duration(3e5)
+	minTripDelay       = time.Duration(1e5)
+	supplyPerPileCount = 1000
+	supplyPileCount    = 5
+	supplyPileTimeout  = time.Duration(1e9)
+)
+
+func randomDuration(random *rand.Rand, min time.Duration, max time.Duration) time.Duration {
+	return min + time.Duration(random.Int63n(int64(max-min)))
+}
+
+// The scenario that runs when no file is specified.
+func defaultScenario(seed int64) *gbb.Scenario {
+	scenario := &gbb.Scenario{Seed: seed}
+	random := rand.New(rand.NewSource(scenario.Seed))
+
+	for ix := 0; ix < gopherCount; ix++ {
+		tripDelay := randomDuration(random, minTripDelay, maxTripDelay)
+
+		scenario.Gophers = append(scenario.Gophers, gbb.GopherSpec{
+			Capacity:     gopherCapacity,
+			ID:           strconv.Itoa(ix),
+			TakeTimeout:  gbb.ScenarioDuration(gopherTakeTimeout),
+			TripDuration: gbb.ScenarioDuration(tripDelay),
+		})
+	}
+
+	for ix := 0; ix < supplyPileCount; ix++ {
+		scenario.SupplyPiles = append(scenario.SupplyPiles, gbb.SupplyPileSpec{
+			Generator: &gbb.BookGeneratorSpec{
+				Count:           supplyPerPileCount,
+				MaxBurnDuration: gbb.ScenarioDuration(maxBurnDuration),
+				MinBurnDuration: gbb.ScenarioDuration(minBurnDuration),
+			},
+			ID:          strconv.Itoa(ix),
+			TakeTimeout: gbb.ScenarioDuration(supplyPileTimeout),
+		})
+	}
+
+	for ix := 0; ix < incineratorCount; ix++ {
+		scenario.Incinerators = append(scenario.Incinerators, gbb.IncineratorSpec{
+			Capacity:    incineratorCap,
+			ID:          strconv.Itoa(ix),
+			MinCapacity: incineratorMinCap,
+		})
+	}
+
+	return scenario
+}
+
+// Load the scenario file specified in the options, or the default scenario if
+// there is none. The seed, dispatch and pile selection flags override what is
+// in the file, and the result is validated again since they may not go with
+// the rest of it.
+func loadScenario(opts *options) (*gbb.Scenario, error) {
+	path := opts.scenarioPath
+
+	if path == "" {
+		seed := time.Now().UnixNano()
+
+		if opts.seedSet {
+			seed = opts.seed
+		}
+
+		scenario := defaultScenario(seed)
+		scenario.Dispatch = opts.dispatch
+		scenario.PileSelection = opts.pileSelect
+
+		if err := scenario.Validate(); err != nil {
+			return nil, err
+		}
+
+		return scenario, nil
+	}
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	scenario, err := gbb.LoadScenario(file)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if opts.seedSet {
+		scenario.Seed = opts.seed
+	}
+
+	if opts.dispatch != "" {
+		scenario.Dispatch = opts.dispatch
+	}
+
+	if opts.pileSelect != "" {
+		scenario.PileSelection = opts.pileSelect
+	}
+
+	if err := scenario.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return scenario, nil
+}
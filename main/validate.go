@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+func runValidate(args []string, stdout io.Writer, stderr io.Writer) int {
+	opts := &options{}
+	flagSet := newFlagSet("validate", stderr, opts)
+
+	flagSet.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: burnbooks validate <scenario file>")
+	}
+
+	if err := parseFlags(flagSet, opts, args); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		return exitError
+	}
+
+	opts.scenarioPath = flagSet.Arg(0)
+	scenario, err := loadScenario(opts)
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	bookCount := 0
+
+	for _, pile := range scenario.SupplyPiles {
+		bookCount += len(pile.Books)
+
+		if pile.Generator != nil {
+			bookCount += int(pile.Generator.Count)
+		}
+	}
+
+	fmt.Fprintf(
+		stdout,
+		"%s is valid: %d piles with %d books, %d gophers, %d incinerators\n",
+		opts.scenarioPath,
+		len(scenario.SupplyPiles),
+		bookCount,
+		len(scenario.Gophers),
+		len(scenario.Incinerators),
+	)
+
+	return exitSuccess
+}
@@ -0,0 +1,90 @@
+package goburnbooks
+
+import "errors"
+
+// ErrNotAccepted is returned when an incinerator receives a Burnable of a
+// category that it does not accept.
+var ErrNotAccepted = errors.New("incinerator does not accept burnable")
+
+// Category represents a kind of Suppliable, such as its material or genre,
+// which incinerators may or may not accept.
+type Category string
+
+// Metadata describes a book beyond what it takes to burn it. Its material and
+// tags are the categories it belongs to.
+type Metadata struct {
+	Author   string     `json:"author,omitempty"`
+	Material Category   `json:"material,omitempty"`
+	Pages    uint       `json:"pages,omitempty"`
+	Tags     []Category `json:"tags,omitempty"`
+	Title    string     `json:"title,omitempty"`
+}
+
+// Categories returns the material, if there is one, followed by the tags.
+func (m Metadata) Categories() []Category {
+	categories := make([]Category, 0, len(m.Tags)+1)
+
+	if m.Material != "" {
+		categories = append(categories, m.Material)
+	}
+
+	return append(categories, m.Tags...)
+}
+
+// Described represents something that carries Metadata. Anything that is not
+// Described has empty Metadata, and therefore no categories.
+type Described interface {
+	Metadata() Metadata
+}
+
+// Get the Metadata of a Burnable or Suppliable.
+func metadataOf(value interface{}) Metadata {
+	if described, ok := value.(Described); ok {
+		return described.Metadata()
+	}
+
+	return Metadata{}
+}
+
+// Get the Metadata of a number of Suppliables, in the same order.
+func suppliesMetadata(supplies []Suppliable) []Metadata {
+	metadata := make([]Metadata, len(supplies))
+
+	for ix, supply := range supplies {
+		metadata[ix] = metadataOf(supply)
+	}
+
+	return metadata
+}
+
+// Check whether something belongs to a category that is accepted, where no
+// accepted categories means that everything is, and to none that is rejected.
+func acceptsCategories(value interface{}, accepts []Category, rejects []Category) bool {
+	if len(accepts) == 0 && len(rejects) == 0 {
+		return true
+	}
+
+	categories := metadataOf(value).Categories()
+
+	for _, category := range categories {
+		for _, rejected := range rejects {
+			if category == rejected {
+				return false
+			}
+		}
+	}
+
+	if len(accepts) == 0 {
+		return true
+	}
+
+	for _, category := range categories {
+		for _, accepted := range accepts {
+			if category == accepted {
+				return true
+			}
+		}
+	}
+
+	return false
+}
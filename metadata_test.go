@@ -0,0 +1,235 @@
+package goburnbooks
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const categoryScenario = `{
+	"dispatch": "roundRobin",
+	"supplyPiles": [
+		{
+			"id": "0",
+			"takeTimeout": "1ms",
+			"books": [
+				{"id": "a", "burnDuration": "1s", "title": "Atlas", "material": "hardcover"},
+				{"id": "b", "burnDuration": "1s", "material": "paper", "tags": ["leaflet"]},
+				{"id": "c", "burnDuration": "1s", "material": "hardcover"},
+				{"id": "d", "burnDuration": "1s", "material": "paper"},
+				{"id": "e", "burnDuration": "1s"}
+			]
+		}
+	],
+	"gophers": [{"id": "0", "capacity": 5, "takeTimeout": "1ms", "tripDuration": "1ms"}],
+	"incinerators": [
+		{"id": "soft", "capacity": 5, "rejects": ["hardcover"]},
+		{"id": "hard", "capacity": 5, "accepts": ["hardcover"]}
+	]
+}`
+
+func Test_RoutingMixedBatches_ShouldBurnWithCompatibleIncinerators(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	ctx := context.Background()
+	clock := NewFakeClock(time.Unix(0, 0))
+	scenario, err := LoadScenario(strings.NewReader(categoryScenario))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	simulation := NewSimulation(ctx, scenario.SimulationParams(ctx, &ScenarioParams{Clock: clock}))
+	defer simulation.Terminate()
+	players := &TestPlayers{simulation: simulation}
+
+	/// When
+	concurrent, err := players.WaitAdvancing(clock, time.Duration(5e9))
+	discrete := RunDiscreteSimulation(scenario.DiscreteParams())
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{"hard": 2, "soft": 3}
+
+	for _, report := range []Report{concurrent, discrete} {
+		if !report.Completed || report.FailedCount != 0 {
+			t.Errorf("Should have burned everything, failed %d", report.FailedCount)
+		}
+
+		if !reflect.DeepEqual(report.IncineratorContrib, expected) {
+			t.Errorf("Should have burned %v, got %v", expected, report.IncineratorContrib)
+		}
+	}
+
+	for _, result := range simulation.SupplyPileGroup().Taken() {
+		for ix, id := range result.SupplyIDs() {
+			if id == "a" && result.Metadata()[ix].Title != "Atlas" {
+				t.Errorf("Should have taken a with its metadata, got %v", result.Metadata()[ix])
+			}
+		}
+	}
+
+	for _, result := range simulation.IncineratorGroup().Burned() {
+		if result.Burned().BurnableID() != "b" {
+			continue
+		}
+
+		if tags := result.Metadata().Tags; !reflect.DeepEqual(tags, []Category{"leaflet"}) {
+			t.Errorf("Should have burned b with its tags, got %v", tags)
+		}
+	}
+}
+
+func Test_RoutingMixedBatchesWithoutStrategy_ShouldBurnWithCompatibleIncinerators(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	sourceCh := make(chan []Burnable)
+
+	group := NewIncineratorGroup(suite.ctx, &IncineratorGroupParams{
+		Incinerators: []FIncinerator{
+			NewIncinerator(suite.ctx, &IncineratorParams{Accepts: []Category{"paper"}, Capacity: 2, ID: "soft"}),
+			NewIncinerator(suite.ctx, &IncineratorParams{Accepts: []Category{"hardcover"}, Capacity: 2, ID: "hard"}),
+		},
+	})
+
+	defer group.Terminate()
+
+	provider := NewBurnableProvider(suite.ctx, &BurnableProviderParams{
+		BurnableProviderRawParams: BurnableProviderRawParams{BPID: "0"},
+		ReceiveBurnableSourceCh:   sourceCh,
+	})
+
+	defer provider.Terminate()
+	burnedBy := make(map[string]string, 0)
+
+	book := func(id string, material Category) Burnable {
+		return NewBook(&BookParams{ID: id, Metadata: Metadata{Material: material}})
+	}
+
+	/// When
+	group.Consume(provider)
+	sourceCh <- []Burnable{book("a", "paper"), book("b", "hardcover"), book("c", "paper"), book("d", "hardcover")}
+
+	for len(burnedBy) < 4 {
+		select {
+		case result := <-group.BurnResultChannel():
+			if result.Err() != nil {
+				t.Fatalf("Should have burned %s, got %v", result.Burned().BurnableID(), result.Err())
+			}
+
+			burnedBy[result.Burned().BurnableID()] = result.IncineratorID()
+
+		case <-time.After(suite.waitDuration):
+			t.Fatalf("Should have burned everything, got %v", burnedBy)
+		}
+	}
+
+	racing := NewIncineratorGroup(suite.ctx, &IncineratorGroupParams{
+		Incinerators: []FIncinerator{NewIncinerator(suite.ctx, &IncineratorParams{Capacity: 1, ID: "any"})},
+	})
+
+	defer racing.Terminate()
+	picky := NewIncinerator(suite.ctx, &IncineratorParams{Rejects: []Category{"paper"}, Capacity: 1, ID: "picky"})
+	defer picky.Terminate()
+	addErr := racing.Add(picky)
+
+	/// Then
+	expected := map[string]string{"a": "soft", "b": "hard", "c": "soft", "d": "hard"}
+
+	if !reflect.DeepEqual(burnedBy, expected) {
+		t.Errorf("Should have burned %v, got %v", expected, burnedBy)
+	}
+
+	if !errors.Is(addErr, ErrRacingCategories) {
+		t.Errorf("Should not have added a picky incinerator to a racing group, got %v", addErr)
+	}
+}
+
+func Test_BurningUnacceptedBooks_ShouldRejectThem(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	sourceCh := make(chan []Burnable)
+
+	incinerator := NewIncinerator(suite.ctx, &IncineratorParams{
+		Accepts:  []Category{"paper"},
+		Capacity: 2,
+		ID:       "0",
+	})
+
+	defer incinerator.Terminate()
+
+	provider := NewBurnableProvider(suite.ctx, &BurnableProviderParams{
+		BurnableProviderRawParams: BurnableProviderRawParams{BPID: "0"},
+		ReceiveBurnableSourceCh:   sourceCh,
+	})
+
+	defer provider.Terminate()
+	errs := make(map[string]error, 0)
+
+	/// When
+	incinerator.Consume(provider)
+
+	sourceCh <- []Burnable{
+		NewBook(&BookParams{ID: "paper", Metadata: Metadata{Material: "paper"}}),
+		NewBook(&BookParams{ID: "hard", Metadata: Metadata{Material: "hardcover"}}),
+	}
+
+	for len(errs) < 2 {
+		select {
+		case result := <-incinerator.BurnResultChannel():
+			errs[result.Burned().BurnableID()] = result.Err()
+
+		case <-time.After(suite.waitDuration):
+			t.Fatalf("Should have dealt with everything, got %v", errs)
+		}
+	}
+
+	/// Then
+	if errs["paper"] != nil {
+		t.Errorf("Should have burned the accepted book, got %v", errs["paper"])
+	}
+
+	if errs["hard"] != ErrNotAccepted {
+		t.Errorf("Should have rejected the other book, got %v", errs["hard"])
+	}
+}
+
+func Test_LoadingScenarioWithUnroutableBooks_ShouldFail(t *testing.T) {
+	/// Setup
+	t.Parallel()
+
+	data := `{
+		"supplyPiles": [
+			{"id": "0", "books": [{"id": "a", "material": "vellum"}]},
+			{"id": "1", "generator": {"count": 1, "material": "paper"}}
+		],
+		"gophers": [{"id": "0", "capacity": 1}],
+		"incinerators": [{"id": "0", "capacity": 1, "accepts": ["paper"]}]
+	}`
+
+	/// When
+	_, err := LoadScenario(strings.NewReader(data))
+
+	/// Then
+	scenarioErr, ok := err.(*ScenarioError)
+
+	if !ok {
+		t.Fatalf("Should have returned a scenario error, got %v", err)
+	}
+
+	expected := []string{
+		"supplyPiles[0].books[0]: no incinerator accepts it",
+	}
+
+	if !reflect.DeepEqual(scenarioErr.Problems, expected) {
+		t.Errorf("Should have listed %v, got %v", expected, scenarioErr.Problems)
+	}
+}
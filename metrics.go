@@ -0,0 +1,276 @@
+package goburnbooks
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics represents something that collects measurements from the actors as
+// they work. Every method may be called concurrently.
+type Metrics interface {
+	// A pile has handed a number of books to a taker.
+	ObserveSupplied(pileID string, count int)
+
+	// A gopher has handed a batch of books to an incinerator, after waiting for
+	// the specified duration for an incinerator to signal ready.
+	ObserveBatch(gopherID string, count int, readyWait time.Duration)
+
+	// A gopher has travelled from a pile to the incinerators.
+	ObserveTrip(gopherID string, duration time.Duration)
+
+	// An incinerator has finished burning a book, including all retries.
+	ObserveBurn(incineratorID string, duration time.Duration, err error)
+
+	// The number of books an incinerator is burning at once has changed.
+	AddBurning(incineratorID string, delta int)
+}
+
+type nopMetrics struct{}
+
+func (nm nopMetrics) ObserveSupplied(pileID string, count int) {}
+
+func (nm nopMetrics) ObserveBatch(gopherID string, count int, readyWait time.Duration) {}
+
+func (nm nopMetrics) ObserveTrip(gopherID string, duration time.Duration) {}
+
+func (nm nopMetrics) ObserveBurn(incineratorID string, duration time.Duration, err error) {}
+
+func (nm nopMetrics) AddBurning(incineratorID string, delta int) {}
+
+// Discard measurements if no Metrics has been specified.
+func metricsOrDefault(metrics Metrics) Metrics {
+	if metrics == nil {
+		return nopMetrics{}
+	}
+
+	return metrics
+}
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the histogram
+// buckets used for durations. They cover burns of a fraction of a millisecond
+// as well as trips of several seconds.
+var DefaultDurationBuckets = []float64{
+	0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25,
+	0.5, 1, 2.5, 5, 10,
+}
+
+// A metric family whose series are told apart by a single label.
+type metricFamily struct {
+	help   string
+	kind   string
+	label  string
+	name   string
+	series map[string]*metricSeries
+}
+
+// A series holds a single value for counters and gauges, or bucket counts for
+// histograms.
+type metricSeries struct {
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+	value        float64
+}
+
+// PrometheusMetrics represents Metrics that can be exposed in the Prometheus
+// text format, either by writing them out or by serving them over HTTP.
+type PrometheusMetrics interface {
+	Metrics
+	http.Handler
+	WriteTo(writer io.Writer) (int64, error)
+}
+
+type prometheusMetrics struct {
+	buckets  []float64
+	families []*metricFamily
+	mutex    sync.Mutex
+
+	batches          *metricFamily
+	batchBooks       *metricFamily
+	burnFailures     *metricFamily
+	burnSeconds      *metricFamily
+	burning          *metricFamily
+	readyWaitSeconds *metricFamily
+	supplied         *metricFamily
+	tripSeconds      *metricFamily
+}
+
+// Only call this while holding the mutex.
+func (pm *prometheusMetrics) seriesOf(family *metricFamily, labelValue string) *metricSeries {
+	series, ok := family.series[labelValue]
+
+	if !ok {
+		series = &metricSeries{bucketCounts: make([]uint64, len(pm.buckets))}
+		family.series[labelValue] = series
+	}
+
+	return series
+}
+
+func (pm *prometheusMetrics) add(family *metricFamily, labelValue string, value float64) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+	pm.seriesOf(family, labelValue).value += value
+}
+
+func (pm *prometheusMetrics) observe(family *metricFamily, labelValue string, d time.Duration) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+	seconds := d.Seconds()
+	series := pm.seriesOf(family, labelValue)
+
+	for ix, bound := range pm.buckets {
+		if seconds <= bound {
+			series.bucketCounts[ix]++
+		}
+	}
+
+	series.count++
+	series.sum += seconds
+}
+
+func (pm *prometheusMetrics) ObserveSupplied(pileID string, count int) {
+	pm.add(pm.supplied, pileID, float64(count))
+}
+
+func (pm *prometheusMetrics) ObserveBatch(gopherID string, count int, readyWait time.Duration) {
+	pm.add(pm.batches, gopherID, 1)
+	pm.add(pm.batchBooks, gopherID, float64(count))
+	pm.observe(pm.readyWaitSeconds, gopherID, readyWait)
+}
+
+func (pm *prometheusMetrics) ObserveTrip(gopherID string, duration time.Duration) {
+	pm.observe(pm.tripSeconds, gopherID, duration)
+}
+
+func (pm *prometheusMetrics) ObserveBurn(incineratorID string, duration time.Duration, err error) {
+	pm.observe(pm.burnSeconds, incineratorID, duration)
+
+	if err != nil {
+		pm.add(pm.burnFailures, incineratorID, 1)
+	}
+}
+
+func (pm *prometheusMetrics) AddBurning(incineratorID string, delta int) {
+	pm.add(pm.burning, incineratorID, float64(delta))
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteTo writes every metric in the Prometheus text format, with families and
+// series in a stable order.
+func (pm *prometheusMetrics) WriteTo(writer io.Writer) (int64, error) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	counter := &countingWriter{writer: writer}
+	buffered := bufio.NewWriter(counter)
+
+	for _, family := range pm.families {
+		labelValues := make([]string, 0)
+
+		for labelValue := range family.series {
+			labelValues = append(labelValues, labelValue)
+		}
+
+		sort.Strings(labelValues)
+		fmt.Fprintf(buffered, "# HELP %s %s\n", family.name, family.help)
+		fmt.Fprintf(buffered, "# TYPE %s %s\n", family.name, family.kind)
+
+		for _, labelValue := range labelValues {
+			series := family.series[labelValue]
+			label := fmt.Sprintf("%s=\"%s\"", family.label, labelEscaper.Replace(labelValue))
+
+			if family.kind != "histogram" {
+				fmt.Fprintf(buffered, "%s{%s} %s\n", family.name, label, formatValue(series.value))
+				continue
+			}
+
+			for ix, bound := range pm.buckets {
+				fmt.Fprintf(
+					buffered,
+					"%s_bucket{%s,le=\"%s\"} %d\n",
+					family.name,
+					label,
+					formatValue(bound),
+					series.bucketCounts[ix],
+				)
+			}
+
+			fmt.Fprintf(buffered, "%s_bucket{%s,le=\"+Inf\"} %d\n", family.name, label, series.count)
+			fmt.Fprintf(buffered, "%s_sum{%s} %s\n", family.name, label, formatValue(series.sum))
+			fmt.Fprintf(buffered, "%s_count{%s} %d\n", family.name, label, series.count)
+		}
+	}
+
+	err := buffered.Flush()
+	return counter.count, err
+}
+
+func (pm *prometheusMetrics) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	pm.WriteTo(writer)
+}
+
+type countingWriter struct {
+	count  int64
+	writer io.Writer
+}
+
+func (cw *countingWriter) Write(data []byte) (int, error) {
+	written, err := cw.writer.Write(data)
+	cw.count += int64(written)
+	return written, err
+}
+
+// NewPrometheusMetrics creates a new PrometheusMetrics. Durations are bucketed
+// with DefaultDurationBuckets if no bucket is specified.
+func NewPrometheusMetrics(buckets ...float64) PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+
+	pm := &prometheusMetrics{buckets: buckets}
+
+	family := func(kind string, name string, label string, help string) *metricFamily {
+		f := &metricFamily{
+			help:   help,
+			kind:   kind,
+			label:  label,
+			name:   name,
+			series: make(map[string]*metricSeries, 0),
+		}
+
+		pm.families = append(pm.families, f)
+		return f
+	}
+
+	pm.supplied = family("counter", "goburnbooks_pile_books_supplied_total", "pile", "Books handed to takers by each pile.")
+	pm.batches = family("counter", "goburnbooks_gopher_batches_total", "gopher", "Batches delivered to incinerators by each gopher.")
+	pm.batchBooks = family("counter", "goburnbooks_gopher_books_delivered_total", "gopher", "Books delivered to incinerators by each gopher.")
+	pm.readyWaitSeconds = family("histogram", "goburnbooks_gopher_ready_wait_seconds", "gopher", "Time each gopher spent waiting for an incinerator to signal ready.")
+	pm.tripSeconds = family("histogram", "goburnbooks_gopher_trip_seconds", "gopher", "Duration of each gopher's trips.")
+	pm.burnSeconds = family("histogram", "goburnbooks_incinerator_burn_seconds", "incinerator", "Time each incinerator took to burn a book, including retries.")
+	pm.burnFailures = family("counter", "goburnbooks_incinerator_burn_failures_total", "incinerator", "Books each incinerator gave up on.")
+	pm.burning = family("gauge", "goburnbooks_incinerator_burning", "incinerator", "Books each incinerator is burning at once.")
+
+	sort.Slice(pm.families, func(i, j int) bool {
+		return pm.families[i].name < pm.families[j].name
+	})
+
+	return pm
+}
@@ -0,0 +1,155 @@
+package goburnbooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_WritingMetrics_ShouldUsePrometheusTextFormat(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	metrics := NewPrometheusMetrics(0.5, 0.001)
+	buffer := &bytes.Buffer{}
+
+	/// When
+	metrics.ObserveSupplied("b", 2)
+	metrics.ObserveSupplied("a\"1", 3)
+	metrics.ObserveSupplied("b", 1)
+	metrics.ObserveBurn("0", time.Millisecond, nil)
+	metrics.ObserveBurn("0", time.Second, errors.New("oops"))
+	metrics.AddBurning("0", 1)
+	metrics.AddBurning("0", 1)
+	metrics.AddBurning("0", -1)
+	written, err := metrics.WriteTo(buffer)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := buffer.String()
+
+	if written != int64(len(output)) {
+		t.Errorf("Should have written %d bytes, got %d", len(output), written)
+	}
+
+	expected := []string{
+		"# TYPE goburnbooks_incinerator_burn_failures_total counter\n" +
+			"goburnbooks_incinerator_burn_failures_total{incinerator=\"0\"} 1\n",
+		"# TYPE goburnbooks_incinerator_burn_seconds histogram\n" +
+			"goburnbooks_incinerator_burn_seconds_bucket{incinerator=\"0\",le=\"0.001\"} 1\n" +
+			"goburnbooks_incinerator_burn_seconds_bucket{incinerator=\"0\",le=\"0.5\"} 1\n" +
+			"goburnbooks_incinerator_burn_seconds_bucket{incinerator=\"0\",le=\"+Inf\"} 2\n" +
+			"goburnbooks_incinerator_burn_seconds_sum{incinerator=\"0\"} 1.001\n" +
+			"goburnbooks_incinerator_burn_seconds_count{incinerator=\"0\"} 2\n",
+		"# TYPE goburnbooks_incinerator_burning gauge\n" +
+			"goburnbooks_incinerator_burning{incinerator=\"0\"} 1\n",
+		"# TYPE goburnbooks_pile_books_supplied_total counter\n" +
+			"goburnbooks_pile_books_supplied_total{pile=\"a\\\"1\"} 3\n" +
+			"goburnbooks_pile_books_supplied_total{pile=\"b\"} 3\n",
+	}
+
+	for _, block := range expected {
+		if !strings.Contains(output, block) {
+			t.Errorf("Should have written %q, got %q", block, output)
+		}
+	}
+
+	if strings.Index(output, "gopher_batches") > strings.Index(output, "pile_books") {
+		t.Errorf("Should have sorted families by name")
+	}
+}
+
+func Test_ServingMetrics_ShouldRespondWithTextFormat(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	metrics := NewPrometheusMetrics()
+	metrics.ObserveTrip("0", time.Second)
+	recorder := httptest.NewRecorder()
+
+	/// When
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	/// Then
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Should have served text format, got %q", contentType)
+	}
+
+	if !strings.Contains(recorder.Body.String(), "goburnbooks_gopher_trip_seconds_count{gopher=\"0\"} 1\n") {
+		t.Errorf("Should have served trip count, got %q", recorder.Body.String())
+	}
+}
+
+func Test_SimulatingWithMetrics_ShouldMeasureEveryActor(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	ctx := context.Background()
+	clock := NewFakeClock(time.Unix(0, 0))
+	metrics := NewPrometheusMetrics().(*prometheusMetrics)
+	scenario, err := LoadScenario(strings.NewReader(validScenario))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	simulation := NewSimulation(ctx, scenario.SimulationParams(ctx, &ScenarioParams{
+		Clock:   clock,
+		Metrics: metrics,
+	}))
+
+	defer simulation.Terminate()
+	players := &TestPlayers{simulation: simulation}
+
+	/// When
+	report, err := players.WaitAdvancing(clock, time.Duration(5e9))
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Completed {
+		t.Fatalf("Should have completed")
+	}
+
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	var supplied, delivered, burns, burning float64
+
+	for _, series := range metrics.supplied.series {
+		supplied += series.value
+	}
+
+	for _, series := range metrics.batchBooks.series {
+		delivered += series.value
+	}
+
+	for _, series := range metrics.burnSeconds.series {
+		burns += float64(series.count)
+	}
+
+	for _, series := range metrics.burning.series {
+		burning += series.value
+	}
+
+	total := float64(report.SupplyCount)
+
+	if supplied != total || delivered != total || burns != total {
+		t.Errorf("Should have measured %v books, got %v/%v/%v", total, supplied, delivered, burns)
+	}
+
+	if burning != 0 {
+		t.Errorf("Should not be burning anything, got %v", burning)
+	}
+
+	for id := range metrics.tripSeconds.series {
+		if metrics.batches.series[id] == nil {
+			t.Errorf("Gopher %s should have delivered batches", id)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package goburnbooks
+
+import (
+	"context"
+	"sync"
+)
+
+// A pileRoute leads a taker's ready signals to one pile. It has a buffer of 1,
+// so that routing never blocks. A signal that is still in the buffer means the
+// pile has yet to get to the taker, since a pile only stops supplying a taker
+// once it is exhausted or draining. Another signal for the same pile is then
+// dropped, because the pile honours the pending one as soon as it can.
+type pileRoute struct {
+	pile    FSupplyPile
+	readyCh chan interface{}
+}
+
+// A pileRouter stands between a taker and the piles of a group, so that each
+// of the taker's ready signals goes to exactly one pile, as picked by a
+// SelectionStrategy. Piles that have something left are preferred over those
+// that may only be restocked later, and a signal that no pile can take is
+// dropped, in which case the taker times out and signals ready again.
+type pileRouter struct {
+	mutex    sync.Mutex
+	routes   []*pileRoute
+	strategy SelectionStrategy
+	taker    SupplyTaker
+}
+
+// Get a taker for a pile to supply.
+func (pr *pileRouter) add(pile FSupplyPile) SupplyTaker {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	route := &pileRoute{pile: pile, readyCh: make(chan interface{}, 1)}
+	pr.routes = append(pr.routes, route)
+	return &routedTaker{SupplyTaker: pr.taker, readyCh: route.readyCh}
+}
+
+func (pr *pileRouter) remove(id string) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+
+	for ix, route := range pr.routes {
+		if route.pile.SupplyPileID() == id {
+			pr.routes = append(pr.routes[:ix], pr.routes[ix+1:]...)
+			return
+		}
+	}
+}
+
+// Only call this while holding the mutex.
+func (pr *pileRouter) candidates(stocked bool) ([]*pileRoute, []PileStock) {
+	routes := make([]*pileRoute, 0)
+	stocks := make([]PileStock, 0)
+
+	for _, route := range pr.routes {
+		remaining := route.pile.Remaining()
+
+		if (stocked && remaining == 0) || (!stocked && route.pile.Exhausted()) {
+			continue
+		}
+
+		routes = append(routes, route)
+		stocks = append(stocks, PileStock{ID: route.pile.SupplyPileID(), Remaining: remaining})
+	}
+
+	return routes, stocks
+}
+
+func (pr *pileRouter) route() {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	routes, stocks := pr.candidates(true)
+
+	if len(routes) == 0 {
+		routes, stocks = pr.candidates(false)
+	}
+
+	if len(routes) == 0 {
+		return
+	}
+
+	route := routes[pr.strategy.Pick(pr.taker.SupplyTakerID(), stocks)]
+
+	select {
+	case route.readyCh <- true:
+	default:
+	}
+}
+
+func (pr *pileRouter) loop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-pr.taker.SendTakeReadyChannel():
+			pr.route()
+		}
+	}
+}
+
+func newPileRouter(taker SupplyTaker, strategy SelectionStrategy) *pileRouter {
+	return &pileRouter{strategy: strategy, taker: taker}
+}
+
+// A routedTaker looks like the taker to a pile, except that ready signals come
+// from the router. Loads still go straight to the taker.
+type routedTaker struct {
+	SupplyTaker
+	readyCh chan interface{}
+}
+
+// The taker itself is terminated by whoever owns it.
+func (rt *routedTaker) Terminate() {}
+
+func (rt *routedTaker) SendTakeReadyChannel() <-chan interface{} {
+	return rt.readyCh
+}
@@ -0,0 +1,177 @@
+package goburnbooks
+
+import (
+	"fmt"
+	"sync"
+)
+
+// PileStock describes what a pile has to offer at some point in time.
+type PileStock struct {
+	ID        string
+	Remaining int
+}
+
+// SelectionStrategy decides which pile a taker that has signalled ready takes
+// from. The candidates are never empty and are listed in the order the piles
+// joined the group, and the returned value is an index into them. Since a
+// group calls this for every taker at once, implementations must be safe for
+// concurrent use.
+type SelectionStrategy interface {
+	Pick(takerID string, candidates []PileStock) int
+}
+
+// DistanceFunc measures how far a pile is from a taker.
+type DistanceFunc func(takerID string, pileID string) float64
+
+// Pick the first candidate that has the lowest score.
+func pickLowestStock(candidates []PileStock, score func(stock PileStock) float64) int {
+	picked := 0
+	lowest := score(candidates[0])
+
+	for ix := 1; ix < len(candidates); ix++ {
+		if value := score(candidates[ix]); value < lowest {
+			picked = ix
+			lowest = value
+		}
+	}
+
+	return picked
+}
+
+type fullestSelection struct{}
+
+// NewFullestSelection returns a SelectionStrategy that picks the pile with the
+// most left.
+func NewFullestSelection() SelectionStrategy {
+	return fullestSelection{}
+}
+
+func (fs fullestSelection) Pick(takerID string, candidates []PileStock) int {
+	return pickLowestStock(candidates, func(stock PileStock) float64 {
+		return -float64(stock.Remaining)
+	})
+}
+
+type inOrderSelection struct{}
+
+// NewInOrderSelection returns a SelectionStrategy that empties the piles one
+// after another, in the order they joined the group.
+func NewInOrderSelection() SelectionStrategy {
+	return inOrderSelection{}
+}
+
+func (ios inOrderSelection) Pick(takerID string, candidates []PileStock) int {
+	return 0
+}
+
+type nearestSelection struct {
+	distance DistanceFunc
+}
+
+// NewNearestSelection returns a SelectionStrategy that picks the pile that is
+// nearest to the taker.
+func NewNearestSelection(distance DistanceFunc) SelectionStrategy {
+	return nearestSelection{distance: distance}
+}
+
+func (ns nearestSelection) Pick(takerID string, candidates []PileStock) int {
+	return pickLowestStock(candidates, func(stock PileStock) float64 {
+		return ns.distance(takerID, stock.ID)
+	})
+}
+
+// Every pile is picked in turn, skipping those that have nothing to offer.
+type roundRobinSelection struct {
+	mutex    sync.Mutex
+	pickedAt map[string]uint64
+	turn     uint64
+}
+
+// NewRoundRobinSelection returns a SelectionStrategy that picks the pile that
+// was picked the longest time ago, or never, by any taker.
+func NewRoundRobinSelection() SelectionStrategy {
+	return &roundRobinSelection{pickedAt: make(map[string]uint64, 0)}
+}
+
+func (rrs *roundRobinSelection) Pick(takerID string, candidates []PileStock) int {
+	rrs.mutex.Lock()
+	defer rrs.mutex.Unlock()
+
+	picked := pickLowestStock(candidates, func(stock PileStock) float64 {
+		return float64(rrs.pickedAt[stock.ID])
+	})
+
+	rrs.turn++
+	rrs.pickedAt[candidates[picked].ID] = rrs.turn
+	return picked
+}
+
+type stickySelection struct {
+	affinity map[string]string
+	mutex    sync.Mutex
+}
+
+// NewStickySelection returns a SelectionStrategy that keeps every taker on the
+// same pile for as long as that pile has something to offer. A taker moves on
+// to the fullest pile otherwise.
+func NewStickySelection() SelectionStrategy {
+	return &stickySelection{affinity: make(map[string]string, 0)}
+}
+
+func (ss *stickySelection) Pick(takerID string, candidates []PileStock) int {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	for ix, stock := range candidates {
+		if stock.ID == ss.affinity[takerID] {
+			return ix
+		}
+	}
+
+	picked := NewFullestSelection().Pick(takerID, candidates)
+	ss.affinity[takerID] = candidates[picked].ID
+	return picked
+}
+
+// These are the names of the supported selection strategies. Racing lets every
+// pile compete for a taker, which is what happens without a strategy.
+const (
+	SelectFullest    = "fullest"
+	SelectInOrder    = "inOrder"
+	SelectNearest    = "nearest"
+	SelectRace       = "race"
+	SelectRoundRobin = "roundRobin"
+	SelectSticky     = "sticky"
+)
+
+// ParseSelectionStrategy returns the SelectionStrategy with the specified name,
+// which measures distances with the specified function if it picks the nearest
+// pile. An empty name means racing, for which the strategy is nil.
+func ParseSelectionStrategy(name string, distance DistanceFunc) (SelectionStrategy, error) {
+	switch name {
+	case "", SelectRace:
+		return nil, nil
+
+	case SelectFullest:
+		return NewFullestSelection(), nil
+
+	case SelectInOrder:
+		return NewInOrderSelection(), nil
+
+	case SelectNearest:
+		if distance == nil {
+			return nil, fmt.Errorf("selection strategy %q needs distances", name)
+		}
+
+		return NewNearestSelection(distance), nil
+
+	case SelectRoundRobin:
+		return NewRoundRobinSelection(), nil
+
+	case SelectSticky:
+		return NewStickySelection(), nil
+
+	default:
+		return nil, fmt.Errorf("unknown selection strategy %q", name)
+	}
+}
@@ -0,0 +1,176 @@
+package goburnbooks
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_PickingPiles_ShouldFollowEachStrategy(t *testing.T) {
+	/// Setup
+	t.Parallel()
+
+	candidates := []PileStock{
+		{ID: "0", Remaining: 5},
+		{ID: "1", Remaining: 20},
+		{ID: "2", Remaining: 10},
+	}
+
+	distances := map[string]float64{"0": 3, "1": 2, "2": 1}
+
+	nearest := NewNearestSelection(func(takerID string, pileID string) float64 {
+		return distances[pileID]
+	})
+
+	roundRobin := NewRoundRobinSelection()
+	rrPicked := make([]int, 0)
+
+	/// When
+	for ix := 0; ix < 4; ix++ {
+		rrPicked = append(rrPicked, roundRobin.Pick("0", candidates))
+	}
+
+	/// Then
+	if picked := NewFullestSelection().Pick("0", candidates); picked != 1 {
+		t.Errorf("Should have picked the fullest pile, got %d", picked)
+	}
+
+	if picked := NewInOrderSelection().Pick("0", candidates[1:]); picked != 0 {
+		t.Errorf("Should have picked the first pile, got %d", picked)
+	}
+
+	if picked := nearest.Pick("0", candidates); picked != 2 {
+		t.Errorf("Should have picked the nearest pile, got %d", picked)
+	}
+
+	if !reflect.DeepEqual(rrPicked, []int{0, 1, 2, 0}) {
+		t.Errorf("Should have picked the piles in turn, got %v", rrPicked)
+	}
+}
+
+func Test_PickingStickyPile_ShouldKeepTakerOnSamePile(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	strategy := NewStickySelection()
+	all := []PileStock{{ID: "0", Remaining: 5}, {ID: "1", Remaining: 20}}
+	rest := []PileStock{{ID: "0", Remaining: 5}}
+
+	/// When
+	first := all[strategy.Pick("a", all)].ID
+	again := all[strategy.Pick("a", []PileStock{{ID: "0", Remaining: 5}, {ID: "1", Remaining: 1}})].ID
+	movedOn := rest[strategy.Pick("a", rest)].ID
+	stayed := all[strategy.Pick("a", all)].ID
+
+	/// Then
+	if first != "1" || again != "1" {
+		t.Errorf("Should have stuck to the fullest pile, got %s and %s", first, again)
+	}
+
+	if movedOn != "0" || stayed != "0" {
+		t.Errorf("Should have moved on once the pile ran out, got %s and %s", movedOn, stayed)
+	}
+
+	if _, err := ParseSelectionStrategy(SelectNearest, nil); err == nil {
+		t.Errorf("Should not have picked the nearest pile without distances")
+	}
+}
+
+func Test_SelectingPilesWithEachStrategy_ShouldTakeAllOnce(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{
+		SelectFullest,
+		SelectInOrder,
+		SelectNearest,
+		SelectRoundRobin,
+		SelectSticky,
+	} {
+		name := name
+
+		t.Run(name, func(t *testing.T) {
+			/// Setup
+			t.Parallel()
+			clock := NewFakeClock(time.Unix(0, 0))
+			suite := NewDefaultTestSuite()
+			suite.clock = clock
+
+			distance := func(takerID string, pileID string) float64 {
+				return float64(len(pileID))
+			}
+
+			suite.selection, _ = ParseSelectionStrategy(name, distance)
+			players := suite.SetUpSystem()
+			defer players.Terminate()
+
+			/// When
+			report, err := players.WaitAdvancing(clock, suite.waitDuration)
+			discreteSuite := NewDefaultTestSuite()
+			discreteSuite.selection, _ = ParseSelectionStrategy(name, distance)
+			discrete := RunDiscreteSimulation(discreteSuite.DiscreteParams(1))
+
+			/// Then
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, report := range []Report{report, discrete} {
+				if !report.Completed || len(report.Violations) > 0 {
+					t.Errorf("Should have burned all once, got %v", report.Violations)
+				}
+
+				if totalContribCount(report.SupplyPileContrib) != players.BookCount() {
+					t.Errorf("Should have taken %d, got %v", players.BookCount(), report.SupplyPileContrib)
+				}
+			}
+
+			t.Logf(
+				"Took %v in %v concurrently and %v in %v discretely",
+				report.SupplyPileContrib,
+				report.Duration,
+				discrete.SupplyPileContrib,
+				discrete.Duration,
+			)
+		})
+	}
+}
+
+func Test_SelectingPilesInOrder_ShouldEmptyEachPileBeforeTheNext(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	clock := NewFakeClock(time.Unix(0, 0))
+	suite := NewDefaultTestSuite()
+	suite.clock = clock
+	suite.selection = NewInOrderSelection()
+	players := suite.SetUpSystem()
+	defer players.Terminate()
+	var outOfOrder int32
+
+	players.supplyPileGroup.AddTakeListener(func(result SupplyTakeResult) {
+		for _, pile := range players.supplyPiles {
+			if pile.SupplyPileID() == result.PileID() {
+				return
+			}
+
+			if pile.Remaining() > 0 {
+				atomic.AddInt32(&outOfOrder, 1)
+			}
+		}
+	})
+
+	/// When
+	report, err := players.WaitAdvancing(clock, suite.waitDuration)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Completed {
+		t.Errorf("Should have completed")
+	}
+
+	if count := atomic.LoadInt32(&outOfOrder); count > 0 {
+		t.Errorf("Should have emptied earlier piles first, got %d takes out of order", count)
+	}
+}
@@ -0,0 +1,404 @@
+package goburnbooks
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Prioritized represents something that should be dealt with before things of
+// lower priority. Anything that is not Prioritized has priority 0.
+type Prioritized interface {
+	Priority() int
+}
+
+// Get the priority of a Burnable or Suppliable.
+func priorityOf(value interface{}) int {
+	if prioritized, ok := value.(Prioritized); ok {
+		return prioritized.Priority()
+	}
+
+	return 0
+}
+
+// A queuedSupply is a Suppliable waiting in a pile. Those of equal priority
+// are supplied earliest deadline first, and then in the order they entered the
+// pile.
+type queuedSupply struct {
+	deadline time.Time
+	priority int
+	queuedAt time.Time
+	sequence uint64
+	supply   Suppliable
+}
+
+// Get the priority of a Suppliable after it has waited until now, which grows
+// by one for every aging interval if there is one.
+func (qs *queuedSupply) agedPriority(now time.Time, aging time.Duration) int {
+	if aging <= 0 {
+		return qs.priority
+	}
+
+	return qs.priority + int(now.Sub(qs.queuedAt)/aging)
+}
+
+// A supplyQueue is a heap of queued Suppliables. With aging, priorities depend
+// on the time, so the heap has to be fixed up for the current time before the
+// first one is looked at.
+type supplyQueue struct {
+	aging    time.Duration
+	now      time.Time
+	supplies []*queuedSupply
+}
+
+// Reorder the queue by the priorities as of now, which is only necessary if
+// they age.
+func (sq *supplyQueue) age(now time.Time) {
+	if sq.aging > 0 {
+		sq.now = now
+		heap.Init(sq)
+	}
+}
+
+func (sq *supplyQueue) first() *queuedSupply {
+	return sq.supplies[0]
+}
+
+func (sq *supplyQueue) Len() int {
+	return len(sq.supplies)
+}
+
+func (sq *supplyQueue) Less(i, j int) bool {
+	first, second := sq.supplies[i], sq.supplies[j]
+	firstPriority := first.agedPriority(sq.now, sq.aging)
+	secondPriority := second.agedPriority(sq.now, sq.aging)
+
+	if firstPriority != secondPriority {
+		return firstPriority > secondPriority
+	}
+
+	if !first.deadline.Equal(second.deadline) {
+		return earlierDeadline(first.deadline, second.deadline)
+	}
+
+	return first.sequence < second.sequence
+}
+
+func (sq *supplyQueue) Swap(i, j int) {
+	sq.supplies[i], sq.supplies[j] = sq.supplies[j], sq.supplies[i]
+}
+
+func (sq *supplyQueue) Push(supply interface{}) {
+	sq.supplies = append(sq.supplies, supply.(*queuedSupply))
+}
+
+func (sq *supplyQueue) Pop() interface{} {
+	old := sq.supplies
+	supply := old[len(old)-1]
+	sq.supplies = old[:len(old)-1]
+	return supply
+}
+
+// A burnTicket represents a Burnable waiting to burn. The grant channel is
+// closed once it may, while the stolen channel is closed once a thief has
+// taken it instead, along with a ticket that the thief has reserved for it.
+// Only one of them is ever closed.
+type burnTicket struct {
+	burnable Burnable
+	deadline time.Time
+	grantCh  chan interface{}
+	handover *burnTicket
+	index    int
+	priority int
+	queuedAt time.Time
+	sequence uint64
+	stolen   bool
+	stolenCh chan interface{}
+	thief    stealer
+	weight   uint
+}
+
+func newBurnTicket(burnable Burnable, now time.Time) *burnTicket {
+	return &burnTicket{
+		burnable: burnable,
+		deadline: deadlineOf(burnable),
+		grantCh:  make(chan interface{}),
+		index:    -1,
+		priority: priorityOf(burnable),
+		queuedAt: now,
+		stolenCh: make(chan interface{}),
+		weight:   weightOf(burnable),
+	}
+}
+
+// Get the priority of a ticket after it has waited until now, which grows by
+// one for every aging interval if there is one.
+func (bt *burnTicket) agedPriority(now time.Time, aging time.Duration) int {
+	if aging <= 0 {
+		return bt.priority
+	}
+
+	return bt.priority + int(now.Sub(bt.queuedAt)/aging)
+}
+
+// Check whether a ticket goes before another as of now.
+func (bt *burnTicket) before(other *burnTicket, now time.Time, aging time.Duration) bool {
+	priority := bt.agedPriority(now, aging)
+	otherPriority := other.agedPriority(now, aging)
+
+	if priority != otherPriority {
+		return priority > otherPriority
+	}
+
+	if !bt.deadline.Equal(other.deadline) {
+		return earlierDeadline(bt.deadline, other.deadline)
+	}
+
+	return bt.sequence < other.sequence
+}
+
+// A burnQueue is a heap of waiting tickets, each of which knows its index so
+// that it can be removed wherever it is. Like a supplyQueue, it has to be fixed
+// up for the current time before the first one is looked at if priorities age.
+type burnQueue struct {
+	aging    time.Duration
+	now      time.Time
+	sequence uint64
+	tickets  []*burnTicket
+}
+
+// Reorder the queue by the priorities as of now, which is only necessary if
+// they age.
+func (bq *burnQueue) age(now time.Time) {
+	bq.now = now
+
+	if bq.aging > 0 {
+		heap.Init(bq)
+	}
+}
+
+func (bq *burnQueue) first() *burnTicket {
+	return bq.tickets[0]
+}
+
+// Remove a ticket wherever it is in the queue, and return false if it was not
+// waiting.
+func (bq *burnQueue) remove(ticket *burnTicket) bool {
+	if ticket.index < 0 {
+		return false
+	}
+
+	heap.Remove(bq, ticket.index)
+	return true
+}
+
+func (bq *burnQueue) Len() int {
+	return len(bq.tickets)
+}
+
+func (bq *burnQueue) Less(i, j int) bool {
+	return bq.tickets[i].before(bq.tickets[j], bq.now, bq.aging)
+}
+
+func (bq *burnQueue) Swap(i, j int) {
+	bq.tickets[i], bq.tickets[j] = bq.tickets[j], bq.tickets[i]
+	bq.tickets[i].index = i
+	bq.tickets[j].index = j
+}
+
+func (bq *burnQueue) Push(ticket interface{}) {
+	queued := ticket.(*burnTicket)
+	queued.index = len(bq.tickets)
+	queued.sequence = bq.sequence
+	bq.sequence++
+	bq.tickets = append(bq.tickets, queued)
+}
+
+func (bq *burnQueue) Pop() interface{} {
+	old := bq.tickets
+	ticket := old[len(old)-1]
+	old[len(old)-1] = nil
+	bq.tickets = old[:len(old)-1]
+	ticket.index = -1
+	return ticket
+}
+
+// A burnScheduler admits burns as long as the total weight burning stays within
+// its capacity, always picking the waiting ticket with the highest aged
+// priority. Among equals, it picks the earliest deadline, and then the ticket
+// that has waited the longest. A ticket that does not fit holds up those behind
+// it until enough weight has been released, so that heavy Burnables are never
+// overtaken forever, and one heavier than the whole capacity burns alone.
+type burnScheduler struct {
+	capacity uint
+	clock    Clock
+	mutex    sync.Mutex
+	running  uint
+	waiting  burnQueue
+}
+
+// Queue a number of Burnables at once, so that the free capacity goes to the
+// most important of them rather than whichever was queued first.
+func (bs *burnScheduler) enqueue(burnables ...Burnable) []*burnTicket {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	now := bs.clock.Now()
+	bs.waiting.now = now
+	tickets := make([]*burnTicket, len(burnables))
+
+	for ix, burnable := range burnables {
+		tickets[ix] = newBurnTicket(burnable, now)
+		heap.Push(&bs.waiting, tickets[ix])
+	}
+
+	bs.grantFitting(now)
+	return tickets
+}
+
+// Queue a Burnable that was stolen from another scheduler, which is never
+// stolen again.
+func (bs *burnScheduler) enqueueStolen(burnable Burnable) *burnTicket {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	now := bs.clock.Now()
+	bs.waiting.now = now
+	ticket := newBurnTicket(burnable, now)
+	ticket.stolen = true
+	heap.Push(&bs.waiting, ticket)
+	bs.grantFitting(now)
+	return ticket
+}
+
+// Get the weight that can still be granted before the capacity is reached,
+// which is 0 if there is no capacity.
+func (bs *burnScheduler) spare() uint {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if bs.running >= bs.capacity {
+		return 0
+	}
+
+	return bs.capacity - bs.running
+}
+
+// Grant tickets for as long as the next one fits. Only call this while holding
+// the mutex.
+func (bs *burnScheduler) grantFitting(now time.Time) {
+	if bs.waiting.Len() == 0 {
+		return
+	}
+
+	bs.waiting.age(now)
+
+	for bs.waiting.Len() > 0 && bs.grantNext() {
+	}
+}
+
+// Grant the next ticket, unless it does not fit. Only call this while holding
+// the mutex, after the queue has been aged.
+func (bs *burnScheduler) grantNext() bool {
+	ticket := bs.waiting.first()
+
+	if !fitsWeight(bs.running, ticket.weight, bs.capacity) {
+		return false
+	}
+
+	bs.running += ticket.weight
+	heap.Pop(&bs.waiting)
+	close(ticket.grantCh)
+	return true
+}
+
+// Take waiting tickets that a thief accepts off the scheduler, in the order they
+// would have been granted, for as long as they fit into some weight. Tickets
+// that were stolen already stay. The weight that was taken is returned.
+func (bs *burnScheduler) steal(thief stealer, weight uint) uint {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	now := bs.clock.Now()
+	aging := bs.waiting.aging
+	candidates := make([]*burnTicket, 0, bs.waiting.Len())
+	stolen := uint(0)
+
+	for _, ticket := range bs.waiting.tickets {
+		if !ticket.stolen && thief.Accepts(ticket.burnable) {
+			candidates = append(candidates, ticket)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].before(candidates[j], now, aging)
+	})
+
+	for _, ticket := range candidates {
+		if !fitsWeight(stolen, ticket.weight, weight) {
+			break
+		}
+
+		stolen += ticket.weight
+		bs.waiting.remove(ticket)
+		ticket.handover = thief.reserve(ticket.burnable)
+		ticket.thief = thief
+		close(ticket.stolenCh)
+	}
+
+	return stolen
+}
+
+// Wait until a ticket is granted, and return false if it is stolen or the
+// context is done first. The ticket no longer waits in either case, and has a
+// thief only if it was stolen.
+func (bs *burnScheduler) acquire(ctx context.Context, ticket *burnTicket) bool {
+	select {
+	case <-ticket.grantCh:
+		return true
+
+	case <-ticket.stolenCh:
+		return false
+
+	case <-ctx.Done():
+	}
+
+	bs.withdraw(ticket)
+	return false
+}
+
+// Stop waiting for a ticket, or hand its weight on if it has been granted.
+func (bs *burnScheduler) withdraw(ticket *burnTicket) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if bs.waiting.remove(ticket) {
+		return
+	}
+
+	// The ticket was granted in the meantime, so hand its weight on, unless it
+	// was stolen instead.
+	if ticket.thief == nil {
+		bs.releaseLocked(ticket.weight)
+	}
+}
+
+// Give up the weight of a granted ticket, which goes to the waiting tickets
+// that fit in turn.
+func (bs *burnScheduler) release(weight uint) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	bs.releaseLocked(weight)
+}
+
+func (bs *burnScheduler) releaseLocked(weight uint) {
+	bs.running -= weight
+	bs.grantFitting(bs.clock.Now())
+}
+
+func newBurnScheduler(capacity uint, aging time.Duration, clock Clock) *burnScheduler {
+	return &burnScheduler{
+		capacity: capacity,
+		clock:    clock,
+		waiting:  burnQueue{aging: aging},
+	}
+}
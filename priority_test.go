@@ -0,0 +1,239 @@
+package goburnbooks
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func booksWithPriorities(priorities map[string]int, ids ...string) []Suppliable {
+	books := make([]Suppliable, len(ids))
+
+	for ix, id := range ids {
+		books[ix] = NewBook(&BookParams{ID: id, Priority: priorities[id]})
+	}
+
+	return books
+}
+
+func Test_SupplyingFromPile_ShouldPutHighestPriorityFirst(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	priorities := map[string]int{"b": 2, "c": 1, "d": 2, "urgent": 9}
+
+	pile := NewSupplyPile(suite.ctx, &SupplyPileParams{
+		Capacity:    5,
+		ID:          "0",
+		Restockable: true,
+		Supply:      booksWithPriorities(priorities, "a", "b", "c", "d"),
+	})
+
+	defer pile.Terminate()
+
+	/// When
+	err := pile.Restock(booksWithPriorities(priorities, "urgent")...)
+	withdrawn := supplyIDs(pile.Withdraw())
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"urgent", "b", "d", "c", "a"}
+
+	if !reflect.DeepEqual(withdrawn, expected) {
+		t.Errorf("Should have supplied %v, got %v", expected, withdrawn)
+	}
+}
+
+func Test_SupplyingFromRestockedPile_ShouldAgeLowPriority(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	priorities := map[string]int{"low": 0}
+
+	// Keep restocking a book of higher priority than the low one, and take one
+	// book every second.
+	supply := func(aging time.Duration) []string {
+		clock := NewFakeClock(time.Unix(0, 0))
+
+		pile := newSupplyPile(suite.ctx, &SupplyPileParams{
+			Capacity:      2,
+			Clock:         clock,
+			ID:            "0",
+			PriorityAging: aging,
+			Restockable:   true,
+			Supply:        booksWithPriorities(priorities, "low"),
+		})
+
+		defer pile.Terminate()
+		taken := make([]string, 0)
+
+		for ix := 0; ix < 6; ix++ {
+			id := strconv.Itoa(ix)
+			priorities[id] = 3
+
+			if err := pile.Restock(booksWithPriorities(priorities, id)...); err != nil {
+				t.Fatal(err)
+			}
+
+			clock.Advance(time.Second)
+			<-pile.supplyCh
+			taken = append(taken, pile.take().SuppliableID())
+		}
+
+		return taken
+	}
+
+	/// When
+	starved := supply(0)
+	aged := supply(time.Second)
+
+	/// Then
+	if expected := []string{"0", "1", "2", "3", "4", "5"}; !reflect.DeepEqual(starved, expected) {
+		t.Errorf("Should have starved the low priority book without aging, got %v", starved)
+	}
+
+	// After 4 seconds, the low priority book has caught up with the one that
+	// has just been restocked, and it entered the pile first.
+	if expected := []string{"0", "1", "2", "low", "3", "4"}; !reflect.DeepEqual(aged, expected) {
+		t.Errorf("Should have supplied %v with aging, got %v", expected, aged)
+	}
+}
+
+func Test_SchedulingBurns_ShouldFollowPriorityAndAge(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	clock := NewFakeClock(time.Unix(0, 0))
+	scheduler := newBurnScheduler(1, time.Second, clock)
+	priorities := map[string]int{"b": 1, "late": 5, "urgent": 5}
+
+	enqueue := func(ids ...string) []*burnTicket {
+		books := booksWithPriorities(priorities, ids...)
+		return scheduler.enqueue(ExtractBurnablesFromSuppliables(books...)...)
+	}
+
+	granted := func(ticket *burnTicket) bool {
+		select {
+		case <-ticket.grantCh:
+			return true
+
+		default:
+			return false
+		}
+	}
+
+	/// When
+	first := enqueue("a", "b")
+	urgent := enqueue("urgent")[0]
+	scheduler.release(1)
+	urgentFirst := granted(urgent) && !granted(first[0])
+	clock.Advance(10 * time.Second)
+	late := enqueue("late")[0]
+	scheduler.release(1)
+
+	/// Then
+	if !granted(first[1]) {
+		t.Errorf("Should have granted the higher priority within the batch first")
+	}
+
+	if !urgentFirst {
+		t.Errorf("Should have granted the urgent ticket before the earlier one")
+	}
+
+	if !granted(first[0]) || granted(late) {
+		t.Errorf("Should have kept the ticket that waited long enough from starving")
+	}
+}
+
+func Test_SchedulingManyBurns_ShouldStayFast(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	scheduler := newBurnScheduler(1, 0, NewFakeClock(time.Unix(0, 0)))
+	bookCount := 50000
+	burnables := make([]Burnable, bookCount)
+
+	for ix := range burnables {
+		burnables[ix] = NewBook(&BookParams{ID: strconv.Itoa(ix), Priority: ix % 7})
+	}
+
+	// Every grant used to scan and splice all waiting tickets, which took
+	// minutes for this many under the race detector.
+	maxWallTime := 10 * time.Second
+	start := time.Now()
+
+	/// When
+	tickets := scheduler.enqueue(burnables...)
+
+	for ix := 1; ix < bookCount; ix += 2 {
+		scheduler.withdraw(tickets[ix])
+	}
+
+	// The first ticket was granted right away, so release all but the last.
+	for ix := 1; ix < bookCount/2; ix++ {
+		scheduler.release(1)
+	}
+
+	elapsed := time.Since(start)
+
+	/// Then
+	for ix, ticket := range tickets {
+		select {
+		case <-ticket.grantCh:
+			if ix%2 == 1 {
+				t.Fatalf("Should not have granted withdrawn ticket %d", ix)
+			}
+
+		default:
+			if ix%2 == 0 {
+				t.Fatalf("Should have granted ticket %d", ix)
+			}
+		}
+	}
+
+	if elapsed > maxWallTime {
+		t.Errorf("Should have scheduled %d burns within %v, took %v", bookCount, maxWallTime, elapsed)
+	}
+}
+
+func Test_BurningPrioritizedBooks_ShouldBurnHighestPriorityFirst(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	sourceCh := make(chan []Burnable)
+	priorities := map[string]int{"a": 0, "b": 3, "c": 1, "d": 2}
+	incinerator := NewIncinerator(suite.ctx, &IncineratorParams{Capacity: 1, ID: "0"})
+	defer incinerator.Terminate()
+
+	provider := NewBurnableProvider(suite.ctx, &BurnableProviderParams{
+		BurnableProviderRawParams: BurnableProviderRawParams{BPID: "0"},
+		ReceiveBurnableSourceCh:   sourceCh,
+	})
+
+	defer provider.Terminate()
+	batch := ExtractBurnablesFromSuppliables(booksWithPriorities(priorities, "a", "b", "c", "d")...)
+	burned := make([]string, 0)
+
+	/// When
+	incinerator.Consume(provider)
+	sourceCh <- batch
+
+	for len(burned) < len(batch) {
+		select {
+		case result := <-incinerator.BurnResultChannel():
+			burned = append(burned, result.Burned().BurnableID())
+
+		case <-time.After(suite.waitDuration):
+			t.Fatalf("Should have burned everything, got %v", burned)
+		}
+	}
+
+	/// Then
+	expected := []string{"b", "d", "c", "a"}
+
+	if !reflect.DeepEqual(burned, expected) {
+		t.Errorf("Should have burned %v, got %v", expected, burned)
+	}
+}
@@ -0,0 +1,66 @@
+package goburnbooks
+
+import (
+	"context"
+)
+
+// Relay represents a stage of a pipeline, in which gophers carry books from
+// some piles to a staging pile instead of the incinerators. The staging pile
+// is then a source for the stage after, which may be another Relay or the
+// gophers of a Simulation, e.g.:
+//
+//	piles -> relay gophers -> staging pile -> gophers -> incinerators
+type Relay interface {
+	Terminator
+	Staging() StagingPile
+	SupplyPileGroup() SupplyPileGroup
+}
+
+// RelayParams represents all the required parameters to build a Relay. The
+// selection strategy decides which pile each gopher takes from, as described
+// in SupplyPileGroup.
+type RelayParams struct {
+	Gophers     []Gopher
+	Selection   SelectionStrategy
+	Staging     StagingPile
+	SupplyPiles []FSupplyPile
+}
+
+type relay struct {
+	*lifecycle
+	RelayParams
+	supplyPileGroup SupplyPileGroup
+}
+
+func (r *relay) Staging() StagingPile {
+	return r.RelayParams.Staging
+}
+
+func (r *relay) SupplyPileGroup() SupplyPileGroup {
+	return r.supplyPileGroup
+}
+
+// NewRelay creates a new Relay, which takes ownership of the piles and gophers
+// it is given and starts relaying right away. The staging pile belongs to the
+// stage after, so it is not terminated along with the relay, but it is closed
+// once every pile the relay started out with has been relayed.
+func NewRelay(ctx context.Context, params *RelayParams) Relay {
+	r := &relay{RelayParams: *params}
+	r.supplyPileGroup = NewSelectiveSupplyPileGroup(ctx, params.Selection, params.SupplyPiles...)
+
+	r.lifecycle = newLifecycle(ctx, nil, func() {
+		for _, gopher := range r.Gophers {
+			gopher.Terminate()
+		}
+
+		r.supplyPileGroup.Terminate()
+	})
+
+	for _, gopher := range params.Gophers {
+		r.supplyPileGroup.Supply(gopher)
+		params.Staging.Consume(gopher)
+	}
+
+	params.Staging.CloseAfter(params.SupplyPiles...)
+	return r
+}
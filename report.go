@@ -0,0 +1,99 @@
+package goburnbooks
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Report represents the outcome of a Simulation. A report that is not complete
+// means the simulation was stopped before all supplies had been burned. Failed
+// burns are counted separately from successful ones.
+//
+// Violations lists every book that was not dealt with exactly once, as found by
+// an Auditor, and the SLA summary counts how many burns missed their deadlines.
+type Report struct {
+	Completed          bool
+	Duration           time.Duration
+	SupplyCount        int
+	BurnedCount        int
+	FailedCount        int
+	BurnedIDs          map[string]int
+	FailedIDs          map[string]int
+	IncineratorContrib map[string]int
+	ProviderContrib    map[string]int
+	SupplyPileContrib  map[string]int
+	SupplyTakerContrib map[string]int
+	SLA                SLASummary
+	Violations         []string
+}
+
+// CompareReports compares two reports of the same scenario, e.g. one from the
+// concurrent runtime and one from the discrete event engine, and describes
+// every way in which their outcomes differ. How the work was spread across
+// gophers and incinerators is expected to vary and is therefore not compared.
+//
+// Neither is how long it took exactly, since the concurrent runtime lets actors
+// race for loads and may thus take a trip more or less than the discrete
+// engine. The durations may differ by the tolerance, as a fraction of the
+// expected duration.
+func CompareReports(expected Report, actual Report, tolerance float64) []string {
+	differences := make([]string, 0)
+
+	compareCount := func(name string, expected int, actual int) {
+		if expected != actual {
+			differences = append(differences, fmt.Sprintf(
+				"%s: expected %d, got %d",
+				name,
+				expected,
+				actual,
+			))
+		}
+	}
+
+	compareMap := func(name string, expected map[string]int, actual map[string]int) {
+		keys := make([]string, 0)
+
+		for key := range expected {
+			keys = append(keys, key)
+		}
+
+		for key := range actual {
+			if _, ok := expected[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			compareCount(fmt.Sprintf("%s %s", name, key), expected[key], actual[key])
+		}
+	}
+
+	if expected.Completed != actual.Completed {
+		differences = append(differences, fmt.Sprintf(
+			"completed: expected %t, got %t",
+			expected.Completed,
+			actual.Completed,
+		))
+	}
+
+	if difference := actual.Duration - expected.Duration; math.Abs(float64(difference)) > tolerance*float64(expected.Duration) {
+		differences = append(differences, fmt.Sprintf(
+			"duration: expected %v within %.0f%%, got %v",
+			expected.Duration,
+			tolerance*100,
+			actual.Duration,
+		))
+	}
+
+	compareCount("supply count", expected.SupplyCount, actual.SupplyCount)
+	compareCount("burned count", expected.BurnedCount, actual.BurnedCount)
+	compareCount("failed count", expected.FailedCount, actual.FailedCount)
+	compareMap("pile", expected.SupplyPileContrib, actual.SupplyPileContrib)
+	compareMap("burned", expected.BurnedIDs, actual.BurnedIDs)
+	compareCount("violation count", len(expected.Violations), len(actual.Violations))
+	return differences
+}
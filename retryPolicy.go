@@ -0,0 +1,40 @@
+package goburnbooks
+
+import (
+	"time"
+)
+
+// RetryPolicy represents how an incinerator retries failed burns. The zero
+// value means a failed burn is never retried.
+type RetryPolicy struct {
+	// The maximum number of attempts, including the first one.
+	MaxAttempts uint
+
+	// The delay before the first retry.
+	Backoff time.Duration
+
+	// Each subsequent retry delay is multiplied by this value. Values below 1
+	// keep the delay constant.
+	BackoffMultiplier float64
+}
+
+func (rp *RetryPolicy) attemptCount() uint {
+	if rp.MaxAttempts == 0 {
+		return 1
+	}
+
+	return rp.MaxAttempts
+}
+
+// Get the delay before the next attempt, given the number of attempts made.
+func (rp *RetryPolicy) backoffAfter(attempts uint) time.Duration {
+	backoff := float64(rp.Backoff)
+
+	if rp.BackoffMultiplier > 1 {
+		for ix := uint(1); ix < attempts; ix++ {
+			backoff *= rp.BackoffMultiplier
+		}
+	}
+
+	return time.Duration(backoff)
+}
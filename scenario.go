@@ -0,0 +1,560 @@
+package goburnbooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// ScenarioDuration represents a duration in a scenario file. It can be written
+// either as a string such as "1.5ms", or as a number of nanoseconds.
+type ScenarioDuration time.Duration
+
+// MarshalJSON writes the duration as a string.
+func (sd ScenarioDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(sd).String())
+}
+
+// UnmarshalJSON reads the duration from either a string or a number.
+func (sd *ScenarioDuration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch value := value.(type) {
+	case float64:
+		*sd = ScenarioDuration(value)
+		return nil
+
+	case string:
+		duration, err := time.ParseDuration(value)
+
+		if err != nil {
+			return err
+		}
+
+		*sd = ScenarioDuration(duration)
+		return nil
+
+	default:
+		return fmt.Errorf("invalid duration %s", string(data))
+	}
+}
+
+// BookSpec describes a single book in a scenario. The deadline is how long
+// after the start of the run the book must have burned, if at all. Its
+// metadata is written alongside, e.g. "title" and "material".
+type BookSpec struct {
+	Metadata
+
+	BurnDuration ScenarioDuration `json:"burnDuration"`
+	Deadline     ScenarioDuration `json:"deadline,omitempty"`
+	FailCount    uint             `json:"failCount,omitempty"`
+	ID           string           `json:"id"`
+	Priority     int              `json:"priority,omitempty"`
+	Weight       uint             `json:"weight,omitempty"`
+}
+
+// BookGeneratorSpec describes a number of books whose burn durations are picked
+// at random between a minimum and a maximum. Generated books are identified by
+// their pile ID and their index within the pile, and share the same priority,
+// deadline, weight, material and tags.
+type BookGeneratorSpec struct {
+	Count           uint             `json:"count"`
+	Deadline        ScenarioDuration `json:"deadline,omitempty"`
+	FailCount       uint             `json:"failCount,omitempty"`
+	Material        Category         `json:"material,omitempty"`
+	MaxBurnDuration ScenarioDuration `json:"maxBurnDuration"`
+	MinBurnDuration ScenarioDuration `json:"minBurnDuration"`
+	Priority        int              `json:"priority,omitempty"`
+	Tags            []Category       `json:"tags,omitempty"`
+	Weight          uint             `json:"weight,omitempty"`
+}
+
+// SupplyPileSpec describes a supply pile in a scenario. A pile either lists
+// its books or specifies how to generate them, but not both. The distance is
+// how far the pile is from the incinerators, where gophers set out from, and
+// is only used to pick the nearest pile. A pile that has a location but no
+// distance is as far as the nearest incinerator.
+type SupplyPileSpec struct {
+	Books       []BookSpec         `json:"books,omitempty"`
+	Distance    float64            `json:"distance,omitempty"`
+	Generator   *BookGeneratorSpec `json:"generator,omitempty"`
+	ID          string             `json:"id"`
+	Location    *Location          `json:"location,omitempty"`
+	TakeTimeout ScenarioDuration   `json:"takeTimeout"`
+}
+
+// GopherSpec describes a gopher in a scenario. A gopher with a speed travels
+// between the locations of the piles and incinerators, which default to the
+// origin, instead of taking the trip duration for every trip. Its capacity is
+// the total weight it carries, like the capacities of incinerators.
+type GopherSpec struct {
+	Capacity     uint             `json:"capacity"`
+	ID           string           `json:"id"`
+	ReturnTrip   bool             `json:"returnTrip,omitempty"`
+	Speed        float64          `json:"speed,omitempty"`
+	TakeTimeout  ScenarioDuration `json:"takeTimeout"`
+	TripDuration ScenarioDuration `json:"tripDuration"`
+}
+
+// RetryPolicySpec describes how an incinerator retries failed burns.
+type RetryPolicySpec struct {
+	Backoff           ScenarioDuration `json:"backoff"`
+	BackoffMultiplier float64          `json:"backoffMultiplier,omitempty"`
+	MaxAttempts       uint             `json:"maxAttempts"`
+}
+
+// IncineratorSpec describes an incinerator in a scenario, which only accepts
+// books of the categories it lists, if any, and never those it rejects.
+type IncineratorSpec struct {
+	Accepts       []Category       `json:"accepts,omitempty"`
+	Capacity      uint             `json:"capacity"`
+	ID            string           `json:"id"`
+	Location      *Location        `json:"location,omitempty"`
+	MinCapacity   uint             `json:"minCapacity"`
+	PriorityAging ScenarioDuration `json:"priorityAging,omitempty"`
+	Rejects       []Category       `json:"rejects,omitempty"`
+	RetryPolicy   *RetryPolicySpec `json:"retryPolicy,omitempty"`
+}
+
+// Scenario describes a whole system, so that it can be loaded from a file
+// instead of being hard-coded. The seed drives book generation, so the same
+// scenario always produces the same books.
+//
+// The dispatch strategy is named as in ParseDispatchStrategy, and a random one
+// is seeded with the scenario's seed. The pile selection strategy is named as
+// in ParseSelectionStrategy. Work stealing only applies to the concurrent
+// simulation, since the discrete one does not model it.
+type Scenario struct {
+	Dispatch      string            `json:"dispatch,omitempty"`
+	Gophers       []GopherSpec      `json:"gophers"`
+	Incinerators  []IncineratorSpec `json:"incinerators"`
+	PileSelection string            `json:"pileSelection,omitempty"`
+	Seed          int64             `json:"seed"`
+	SupplyPiles   []SupplyPileSpec  `json:"supplyPiles"`
+	WorkStealing  bool              `json:"workStealing,omitempty"`
+}
+
+// ScenarioError lists every problem found while validating a Scenario.
+type ScenarioError struct {
+	Problems []string
+}
+
+func (se *ScenarioError) Error() string {
+	return fmt.Sprintf(
+		"invalid scenario:\n  %s",
+		strings.Join(se.Problems, "\n  "),
+	)
+}
+
+// LoadScenario reads a JSON scenario and validates it. Unknown fields are
+// rejected, so that a misspelt field does not silently fall back to zero.
+func LoadScenario(reader io.Reader) (*Scenario, error) {
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	scenario := &Scenario{}
+
+	if err := decoder.Decode(scenario); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %v", err)
+	}
+
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
+
+	return scenario, nil
+}
+
+// Validate checks that the scenario describes a system that can run to
+// completion, and returns a ScenarioError otherwise.
+func (s *Scenario) Validate() error {
+	problems := make([]string, 0)
+
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	checkID := func(ids map[string]bool, path string, id string) {
+		if id == "" {
+			report("%s: id must not be empty", path)
+		} else if ids[id] {
+			report("%s: duplicate id %q", path, id)
+		}
+
+		ids[id] = true
+	}
+
+	if len(s.SupplyPiles) == 0 {
+		report("supplyPiles: must have at least one pile")
+	}
+
+	if len(s.Gophers) == 0 {
+		report("gophers: must have at least one gopher")
+	}
+
+	if len(s.Incinerators) == 0 {
+		report("incinerators: must have at least one incinerator")
+	}
+
+	if _, err := ParseDispatchStrategy(s.Dispatch, s.Seed); err != nil {
+		report("dispatch: %v", err)
+	}
+
+	checkAccepted := func(path string, metadata Metadata) {
+		if len(s.Incinerators) == 0 {
+			return
+		}
+
+		described := NewBook(&BookParams{Metadata: metadata})
+
+		for _, incinerator := range s.Incinerators {
+			if acceptsCategories(described, incinerator.Accepts, incinerator.Rejects) {
+				return
+			}
+		}
+
+		report("%s: no incinerator accepts it", path)
+	}
+
+	if _, err := s.selection(); err != nil {
+		report("pileSelection: %v", err)
+	}
+
+	pileIDs := make(map[string]bool, 0)
+	bookIDs := make(map[string]bool, 0)
+
+	for ix, pile := range s.SupplyPiles {
+		path := fmt.Sprintf("supplyPiles[%d]", ix)
+		checkID(pileIDs, path, pile.ID)
+
+		if pile.TakeTimeout < 0 {
+			report("%s: takeTimeout must not be negative", path)
+		}
+
+		if pile.Distance < 0 {
+			report("%s: distance must not be negative", path)
+		}
+
+		if pile.Generator != nil && len(pile.Books) > 0 {
+			report("%s: must not have both books and a generator", path)
+		}
+
+		for jx, book := range pile.Books {
+			bookPath := fmt.Sprintf("%s.books[%d]", path, jx)
+			checkID(bookIDs, bookPath, book.ID)
+
+			if book.BurnDuration < 0 {
+				report("%s: burnDuration must not be negative", bookPath)
+			}
+
+			if book.Deadline < 0 {
+				report("%s: deadline must not be negative", bookPath)
+			}
+
+			checkAccepted(bookPath, book.Metadata)
+		}
+
+		if generator := pile.Generator; generator != nil {
+			genPath := fmt.Sprintf("%s.generator", path)
+
+			if generator.MinBurnDuration < 0 {
+				report("%s: minBurnDuration must not be negative", genPath)
+			}
+
+			if generator.MaxBurnDuration < generator.MinBurnDuration {
+				report("%s: maxBurnDuration must not be less than minBurnDuration", genPath)
+			}
+
+			if generator.Deadline < 0 {
+				report("%s: deadline must not be negative", genPath)
+			}
+
+			checkAccepted(genPath, Metadata{Material: generator.Material, Tags: generator.Tags})
+
+			for jx := 0; jx < int(generator.Count); jx++ {
+				checkID(bookIDs, genPath, generatedBookID(pile.ID, jx))
+			}
+		}
+	}
+
+	gopherIDs := make(map[string]bool, 0)
+
+	for ix, gopher := range s.Gophers {
+		path := fmt.Sprintf("gophers[%d]", ix)
+		checkID(gopherIDs, path, gopher.ID)
+
+		if gopher.Capacity == 0 {
+			report("%s: capacity must be positive", path)
+		}
+
+		if gopher.TakeTimeout < 0 {
+			report("%s: takeTimeout must not be negative", path)
+		}
+
+		if gopher.TripDuration < 0 {
+			report("%s: tripDuration must not be negative", path)
+		}
+
+		if gopher.Speed < 0 {
+			report("%s: speed must not be negative", path)
+		}
+	}
+
+	incineratorIDs := make(map[string]bool, 0)
+
+	for ix, incinerator := range s.Incinerators {
+		path := fmt.Sprintf("incinerators[%d]", ix)
+		checkID(incineratorIDs, path, incinerator.ID)
+
+		if incinerator.Capacity == 0 {
+			report("%s: capacity must be positive", path)
+		}
+
+		if incinerator.MinCapacity > incinerator.Capacity {
+			report("%s: minCapacity must not exceed capacity", path)
+		}
+
+		if incinerator.PriorityAging < 0 {
+			report("%s: priorityAging must not be negative", path)
+		}
+
+		if policy := incinerator.RetryPolicy; policy != nil && policy.Backoff < 0 {
+			report("%s.retryPolicy: backoff must not be negative", path)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ScenarioError{Problems: problems}
+	}
+
+	return nil
+}
+
+func generatedBookID(pileID string, index int) string {
+	return fmt.Sprintf("%s-%d", pileID, index)
+}
+
+// Get the location of a pile or incinerator, which is the origin if unset.
+func locationOrDefault(location *Location) Location {
+	if location == nil {
+		return Location{}
+	}
+
+	return *location
+}
+
+// Build the pile selection strategy, which measures distances from the
+// incinerators regardless of the gopher.
+func (s *Scenario) selection() (SelectionStrategy, error) {
+	distances := make(map[string]float64, len(s.SupplyPiles))
+
+	for _, pile := range s.SupplyPiles {
+		distances[pile.ID] = pile.Distance
+
+		if pile.Distance != 0 || pile.Location == nil {
+			continue
+		}
+
+		for ix, incinerator := range s.Incinerators {
+			distance := pile.Location.Distance(locationOrDefault(incinerator.Location))
+
+			if ix == 0 || distance < distances[pile.ID] {
+				distances[pile.ID] = distance
+			}
+		}
+	}
+
+	return ParseSelectionStrategy(s.PileSelection, func(takerID string, pileID string) float64 {
+		return distances[pileID]
+	})
+}
+
+// Describe the actors with the specified dependencies, which may be nil.
+// Deadlines are measured from the time on the clock, or from the Unix epoch
+// without one.
+func (s *Scenario) params(clock Clock, logger Logger, metrics Metrics) *DiscreteSimulationParams {
+	random := rand.New(rand.NewSource(s.Seed))
+	dispatch, _ := ParseDispatchStrategy(s.Dispatch, s.Seed)
+	selection, _ := s.selection()
+	start := time.Unix(0, 0)
+
+	if clock != nil {
+		start = clock.Now()
+	}
+
+	deadline := func(after ScenarioDuration) time.Time {
+		if after == 0 {
+			return time.Time{}
+		}
+
+		return start.Add(time.Duration(after))
+	}
+
+	params := &DiscreteSimulationParams{
+		Dispatch:  dispatch,
+		Seed:      s.Seed,
+		Selection: selection,
+		Start:     start,
+	}
+
+	for _, pile := range s.SupplyPiles {
+		supplies := make([]Suppliable, 0)
+
+		for _, book := range pile.Books {
+			supplies = append(supplies, NewBook(&BookParams{
+				BurnDuration: time.Duration(book.BurnDuration),
+				Clock:        clock,
+				Deadline:     deadline(book.Deadline),
+				FailCount:    book.FailCount,
+				ID:           book.ID,
+				Metadata:     book.Metadata,
+				Priority:     book.Priority,
+				Weight:       book.Weight,
+			}))
+		}
+
+		if generator := pile.Generator; generator != nil {
+			min := time.Duration(generator.MinBurnDuration)
+			spread := int64(generator.MaxBurnDuration - generator.MinBurnDuration)
+
+			for jx := 0; jx < int(generator.Count); jx++ {
+				duration := min
+
+				if spread > 0 {
+					duration += time.Duration(random.Int63n(spread))
+				}
+
+				supplies = append(supplies, NewBook(&BookParams{
+					BurnDuration: duration,
+					Clock:        clock,
+					Deadline:     deadline(generator.Deadline),
+					FailCount:    generator.FailCount,
+					ID:           generatedBookID(pile.ID, jx),
+					Metadata:     Metadata{Material: generator.Material, Tags: generator.Tags},
+					Priority:     generator.Priority,
+					Weight:       generator.Weight,
+				}))
+			}
+		}
+
+		params.SupplyPiles = append(params.SupplyPiles, SupplyPileParams{
+			Clock:       clock,
+			ID:          pile.ID,
+			Location:    locationOrDefault(pile.Location),
+			Logger:      logger,
+			Metrics:     metrics,
+			Supply:      supplies,
+			TakeTimeout: time.Duration(pile.TakeTimeout),
+		})
+	}
+
+	for _, gopher := range s.Gophers {
+		params.Gophers = append(params.Gophers, GopherParams{
+			BurnableProviderRawParams: BurnableProviderRawParams{BPID: gopher.ID},
+			SupplyTakerRawParams: SupplyTakerRawParams{
+				Cap:         gopher.Capacity,
+				STID:        gopher.ID,
+				TakeTimeout: time.Duration(gopher.TakeTimeout),
+			},
+			Clock:        clock,
+			Logger:       logger,
+			Metrics:      metrics,
+			ReturnTrip:   gopher.ReturnTrip,
+			Speed:        gopher.Speed,
+			TripDuration: time.Duration(gopher.TripDuration),
+		})
+	}
+
+	for _, incinerator := range s.Incinerators {
+		var retryPolicy RetryPolicy
+
+		if policy := incinerator.RetryPolicy; policy != nil {
+			retryPolicy = RetryPolicy{
+				Backoff:           time.Duration(policy.Backoff),
+				BackoffMultiplier: policy.BackoffMultiplier,
+				MaxAttempts:       policy.MaxAttempts,
+			}
+		}
+
+		params.Incinerators = append(params.Incinerators, IncineratorParams{
+			Accepts:       incinerator.Accepts,
+			Capacity:      incinerator.Capacity,
+			Clock:         clock,
+			ID:            incinerator.ID,
+			Location:      locationOrDefault(incinerator.Location),
+			Logger:        logger,
+			Metrics:       metrics,
+			MinCapacity:   incinerator.MinCapacity,
+			PriorityAging: time.Duration(incinerator.PriorityAging),
+			Rejects:       incinerator.Rejects,
+			RetryPolicy:   retryPolicy,
+		})
+	}
+
+	return params
+}
+
+// DiscreteParams describes the scenario for the discrete event engine.
+func (s *Scenario) DiscreteParams() *DiscreteSimulationParams {
+	return s.params(nil, nil, nil)
+}
+
+// ScenarioParams represents the dependencies shared by every actor built from
+// a Scenario. The clock defaults to the real clock, while the logger and
+// metrics discard everything if nil.
+type ScenarioParams struct {
+	Clock   Clock
+	Logger  Logger
+	Metrics Metrics
+}
+
+// SimulationParams builds the actors described by the scenario, ready to be
+// handed to NewSimulation along with an auditor that knows every book.
+func (s *Scenario) SimulationParams(
+	ctx context.Context,
+	scenarioParams *ScenarioParams,
+) *SimulationParams {
+	clock := clockOrDefault(scenarioParams.Clock)
+	logger := scenarioParams.Logger
+
+	if logger == nil {
+		logger = NewLogger(false)
+	}
+
+	params := s.params(clock, logger, scenarioParams.Metrics)
+
+	simParams := &SimulationParams{
+		Auditor:      NewAuditor(&AuditorParams{Logger: logger}),
+		Clock:        clock,
+		Dispatch:     params.Dispatch,
+		Logger:       logger,
+		Selection:    params.Selection,
+		WorkStealing: s.WorkStealing,
+	}
+
+	for ix := range params.SupplyPiles {
+		pileParams := &params.SupplyPiles[ix]
+		simParams.Auditor.RecordSupply(pileParams.ID, supplyIDs(pileParams.Supply)...)
+		pile := NewSupplyPile(ctx, pileParams)
+		simParams.SupplyPiles = append(simParams.SupplyPiles, pile)
+	}
+
+	for ix := range params.Gophers {
+		gopher := NewGopher(ctx, &params.Gophers[ix])
+		simParams.Gophers = append(simParams.Gophers, gopher)
+	}
+
+	for ix := range params.Incinerators {
+		incinerator := NewIncinerator(ctx, &params.Incinerators[ix])
+		simParams.Incinerators = append(simParams.Incinerators, incinerator)
+	}
+
+	return simParams
+}
@@ -0,0 +1,140 @@
+package goburnbooks
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const validScenario = `{
+	"seed": 1,
+	"supplyPiles": [
+		{
+			"id": "0",
+			"takeTimeout": "1ms",
+			"generator": {"count": 100, "minBurnDuration": "1ms", "maxBurnDuration": "2ms"}
+		},
+		{
+			"id": "1",
+			"takeTimeout": 1000000,
+			"books": [{"id": "a", "burnDuration": "1ms"}, {"id": "b", "burnDuration": "1ms"}]
+		}
+	],
+	"gophers": [{"id": "0", "capacity": 10, "takeTimeout": "1ms", "tripDuration": "1ms"}],
+	"incinerators": [{"id": "0", "capacity": 5, "minCapacity": 2}]
+}`
+
+func Test_LoadingValidScenario_ShouldRunToCompletion(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	ctx := context.Background()
+	clock := NewFakeClock(time.Unix(0, 0))
+
+	/// When
+	scenario, err := LoadScenario(strings.NewReader(validScenario))
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if scenario.SupplyPiles[1].TakeTimeout != ScenarioDuration(time.Millisecond) {
+		t.Errorf("Should have read duration in nanoseconds")
+	}
+
+	simulation := NewSimulation(ctx, scenario.SimulationParams(ctx, &ScenarioParams{Clock: clock}))
+	defer simulation.Terminate()
+	players := &TestPlayers{simulation: simulation}
+	report, err := players.WaitAdvancing(clock, time.Duration(5e9))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Completed || report.BurnedCount != 102 {
+		t.Errorf("Should have burned 102, got %d", report.BurnedCount)
+	}
+
+	for _, difference := range CompareReports(report, RunDiscreteSimulation(scenario.DiscreteParams()), 0.05) {
+		t.Error(difference)
+	}
+}
+
+func Test_BuildingScenarioWithSameSeed_ShouldGenerateSameBooks(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	scenario, err := LoadScenario(strings.NewReader(validScenario))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	/// When
+	params1 := scenario.DiscreteParams()
+	params2 := scenario.DiscreteParams()
+
+	/// Then
+	if !reflect.DeepEqual(params1, params2) {
+		t.Errorf("Should have generated the same params")
+	}
+}
+
+func Test_LoadingInvalidScenario_ShouldListAllProblems(t *testing.T) {
+	/// Setup
+	t.Parallel()
+
+	data := `{
+		"dispatch": "fastest",
+		"pileSelection": "closest",
+		"supplyPiles": [
+			{"id": "0", "books": [{"id": "a"}], "distance": -1, "generator": {"count": 1}},
+			{"id": "0", "books": [{"id": "a", "burnDuration": "-1s"}]}
+		],
+		"gophers": [{"id": "0", "capacity": 0, "speed": -1}],
+		"incinerators": [{"id": "", "capacity": 2, "minCapacity": 3}]
+	}`
+
+	/// When
+	_, err := LoadScenario(strings.NewReader(data))
+
+	/// Then
+	scenarioErr, ok := err.(*ScenarioError)
+
+	if !ok {
+		t.Fatalf("Should have returned a scenario error, got %v", err)
+	}
+
+	expected := []string{
+		"dispatch: unknown dispatch strategy \"fastest\"",
+		"pileSelection: unknown selection strategy \"closest\"",
+		"supplyPiles[0]: distance must not be negative",
+		"supplyPiles[0]: must not have both books and a generator",
+		"supplyPiles[1]: duplicate id \"0\"",
+		"supplyPiles[1].books[0]: duplicate id \"a\"",
+		"supplyPiles[1].books[0]: burnDuration must not be negative",
+		"gophers[0]: capacity must be positive",
+		"gophers[0]: speed must not be negative",
+		"incinerators[0]: id must not be empty",
+		"incinerators[0]: minCapacity must not exceed capacity",
+	}
+
+	if !reflect.DeepEqual(scenarioErr.Problems, expected) {
+		t.Errorf("Should have listed %v, got %v", expected, scenarioErr.Problems)
+	}
+}
+
+func Test_LoadingScenarioWithUnknownField_ShouldFail(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	data := `{"gophers": [{"id": "0", "capcity": 1}]}`
+
+	/// When
+	_, err := LoadScenario(strings.NewReader(data))
+
+	/// Then
+	if err == nil || !strings.Contains(err.Error(), "capcity") {
+		t.Errorf("Should have complained about unknown field, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package goburnbooks
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"time"
@@ -12,6 +13,7 @@ type TestPlayers struct {
 	gophers          []Gopher
 	incinerators     []FIncinerator
 	incineratorGroup IncineratorGroup
+	simulation       Simulation
 	supplyPiles      []FSupplyPile
 	supplyPileGroup  SupplyPileGroup
 }
@@ -28,10 +30,28 @@ func (tp *TestPlayers) BookCount() int {
 	return len(tp.bookIds)
 }
 
+func (tp *TestPlayers) Terminate() {
+	tp.simulation.Terminate()
+}
+
+// Wait for the simulation to finish, or time out after the specified duration.
+func (tp *TestPlayers) Wait(timeout time.Duration) (Report, error) {
+	select {
+	case report := <-tp.simulation.Done():
+		return report, nil
+
+	case <-time.After(timeout):
+		return Report{}, fmt.Errorf("Simulation timed out after %v", timeout)
+	}
+}
+
 type TestSuite struct {
 	burnDuration            time.Duration
 	burnRounds              uint
+	clock                   Clock
 	contribPercentThreshold float64
+	ctx                     context.Context
+	dispatch                DispatchStrategy
 	gopherCapacity          uint
 	gopherCount             uint
 	gopherTakeTimeout       time.Duration
@@ -40,6 +60,7 @@ type TestSuite struct {
 	incineratorMinCap       uint
 	integrationWaitDuration time.Duration
 	logger                  Logger
+	selection               SelectionStrategy
 	supplyPerPileCount      uint
 	supplyPileCount         uint
 	supplyPileTimeout       time.Duration
@@ -47,6 +68,29 @@ type TestSuite struct {
 	waitDuration            time.Duration
 }
 
+// Wait for the simulation to finish while advancing the fake clock, waiting for
+// the actors to go idle in between so that they react to every timer that fires
+// before the next one does.
+func (tp *TestPlayers) WaitAdvancing(
+	clock FakeClock,
+	timeout time.Duration,
+) (Report, error) {
+	timeoutCh := time.After(timeout)
+
+	for {
+		select {
+		case report := <-tp.simulation.Done():
+			return report, nil
+
+		case <-timeoutCh:
+			return Report{}, fmt.Errorf("Simulation timed out after %v", timeout)
+
+		default:
+			clock.AdvanceToNextWhenIdle(500 * time.Microsecond)
+		}
+	}
+}
+
 func (ts *TestSuite) TotalSupplyCount() uint {
 	return ts.supplyPerPileCount * ts.supplyPileCount
 }
@@ -64,11 +108,12 @@ func (ts *TestSuite) Gophers() []Gopher {
 				STID:        strconv.Itoa(ix),
 				TakeTimeout: ts.gopherTakeTimeout,
 			},
+			Clock:        ts.clock,
 			Logger:       ts.logger,
 			TripDuration: ts.tripDelay,
 		}
 
-		gopher := NewGopher(&gParams)
+		gopher := NewGopher(ts.ctx, &gParams)
 		gophers[ix] = gopher
 	}
 
@@ -85,7 +130,7 @@ func (ts *TestSuite) SupplyPiles() ([]FSupplyPile, []Book, []string) {
 
 		for jx := range supplies {
 			id := fmt.Sprintf("%d-%d", ix, jx)
-			bParams := BookParams{BurnDuration: ts.burnDuration, ID: id}
+			bParams := BookParams{BurnDuration: ts.burnDuration, Clock: ts.clock, ID: id}
 			book := NewBook(&bParams)
 			supplies[jx] = book
 			allBooks = append(allBooks, book)
@@ -93,6 +138,7 @@ func (ts *TestSuite) SupplyPiles() ([]FSupplyPile, []Book, []string) {
 		}
 
 		pParams := SupplyPileParams{
+			Clock:              ts.clock,
 			Logger:             ts.logger,
 			Supply:             supplies,
 			ID:                 strconv.Itoa(ix),
@@ -100,7 +146,7 @@ func (ts *TestSuite) SupplyPiles() ([]FSupplyPile, []Book, []string) {
 			TakeTimeout:        ts.supplyPileTimeout,
 		}
 
-		pile := NewSupplyPile(&pParams)
+		pile := NewSupplyPile(ts.ctx, &pParams)
 		piles[ix] = pile
 	}
 
@@ -113,12 +159,13 @@ func (ts *TestSuite) Incinerators() []FIncinerator {
 	for ix := range incinerators {
 		iParams := IncineratorParams{
 			Capacity:    ts.incineratorCap,
+			Clock:       ts.clock,
 			ID:          strconv.Itoa(ix),
 			Logger:      ts.logger,
 			MinCapacity: ts.incineratorMinCap,
 		}
 
-		incinerator := NewIncinerator(&iParams)
+		incinerator := NewIncinerator(ts.ctx, &iParams)
 		incinerators[ix] = incinerator
 	}
 
@@ -131,7 +178,6 @@ func (ts *TestSuite) BurnableProviders() []BurnableProvider {
 	for pix := range providers {
 		provideCh := make(chan []Burnable)
 		prRawParams := BurnableProviderRawParams{BPID: strconv.Itoa(pix)}
-		readyCh := make(chan interface{})
 
 		prParams := BurnableProviderParams{
 			BurnableProviderRawParams: prRawParams,
@@ -139,7 +185,7 @@ func (ts *TestSuite) BurnableProviders() []BurnableProvider {
 			ReceiveBurnableSourceCh:   provideCh,
 		}
 
-		provider := NewBurnableProvider(&prParams)
+		provider := NewBurnableProvider(ts.ctx, &prParams)
 
 		go func(ix int) {
 			for j := 0; j < int(ts.burnRounds); j++ {
@@ -148,6 +194,7 @@ func (ts *TestSuite) BurnableProviders() []BurnableProvider {
 				for bix := range burnables {
 					bParams := BookParams{
 						BurnDuration: ts.burnDuration,
+						Clock:        ts.clock,
 						ID:           fmt.Sprintf("%d-%d-%d", ix, j, bix),
 					}
 
@@ -155,17 +202,16 @@ func (ts *TestSuite) BurnableProviders() []BurnableProvider {
 					burnables[bix] = burnable
 				}
 
-				provideCh <- burnables
+				select {
+				case provideCh <- burnables:
+				case <-ts.ctx.Done():
+					return
+				}
+
 				time.Sleep(1e5)
 			}
 		}(pix)
 
-		go func() {
-			for {
-				<-readyCh
-			}
-		}()
-
 		providers[pix] = provider
 	}
 
@@ -177,11 +223,11 @@ func (ts *TestSuite) SupplyTakers() []SupplyTaker {
 	totalSupplyCount := ts.TotalSupplyCount()
 
 	for ix := range supplyTakers {
-		readyCh := make(chan interface{})
-
 		stRawParams := SupplyTakerRawParams{
-			Cap:  ts.gopherCapacity,
-			STID: strconv.Itoa(ix),
+			Cap:         ts.gopherCapacity,
+			Clock:       ts.clock,
+			STID:        strconv.Itoa(ix),
+			TakeTimeout: ts.gopherTakeTimeout,
 		}
 
 		stParams := &SupplyTakerParams{
@@ -190,48 +236,99 @@ func (ts *TestSuite) SupplyTakers() []SupplyTaker {
 			STLogger:             ts.logger,
 		}
 
-		// Assume that the supply taker takes repeatedly at a specified delay.
-		go func() {
-			for {
-				time.Sleep(1e5)
-				readyCh <- true
-			}
-		}()
-
-		supply := NewSupplyTaker(stParams)
+		supply := NewSupplyTaker(ts.ctx, stParams)
 		supplyTakers[ix] = supply
 	}
 
 	return supplyTakers
 }
 
+// Describe the same system as SetUpSystem, to be run by the discrete event
+// engine instead.
+func (ts *TestSuite) DiscreteParams(seed int64) *DiscreteSimulationParams {
+	params := &DiscreteSimulationParams{
+		Dispatch:  ts.dispatch,
+		Seed:      seed,
+		Selection: ts.selection,
+	}
+
+	for ix := 0; ix < int(ts.gopherCount); ix++ {
+		params.Gophers = append(params.Gophers, GopherParams{
+			BurnableProviderRawParams: BurnableProviderRawParams{
+				BPID: strconv.Itoa(ix),
+			},
+			SupplyTakerRawParams: SupplyTakerRawParams{
+				Cap:         ts.gopherCapacity,
+				STID:        strconv.Itoa(ix),
+				TakeTimeout: ts.gopherTakeTimeout,
+			},
+			TripDuration: ts.tripDelay,
+		})
+	}
+
+	for ix := 0; ix < int(ts.supplyPileCount); ix++ {
+		supplies := make([]Suppliable, ts.supplyPerPileCount)
+
+		for jx := range supplies {
+			id := fmt.Sprintf("%d-%d", ix, jx)
+			supplies[jx] = NewBook(&BookParams{BurnDuration: ts.burnDuration, ID: id})
+		}
+
+		params.SupplyPiles = append(params.SupplyPiles, SupplyPileParams{
+			Supply:      supplies,
+			ID:          strconv.Itoa(ix),
+			TakeTimeout: ts.supplyPileTimeout,
+		})
+	}
+
+	for ix := 0; ix < int(ts.incineratorCount); ix++ {
+		params.Incinerators = append(params.Incinerators, IncineratorParams{
+			Capacity:    ts.incineratorCap,
+			ID:          strconv.Itoa(ix),
+			MinCapacity: ts.incineratorMinCap,
+		})
+	}
+
+	return params
+}
+
 func (ts *TestSuite) SetUpSystem() *TestPlayers {
 	gophers := ts.Gophers()
 	piles, books, bookIds := ts.SupplyPiles()
-	pileGroup := NewSupplyPileGroup(piles...)
 	incinerators := ts.Incinerators()
 	totalSupplyCount := ts.TotalSupplyCount()
 
-	igParams := IncineratorGroupParams{
+	auditor := NewAuditor(&AuditorParams{Logger: ts.logger})
+
+	// Books are created pile by pile, so each pile owns a contiguous range.
+	for ix, book := range books {
+		pile := piles[ix/int(ts.supplyPerPileCount)]
+		auditor.RecordSupply(pile.SupplyPileID(), book.SuppliableID())
+	}
+
+	simParams := SimulationParams{
+		Auditor:            auditor,
 		BurnResultCapacity: totalSupplyCount,
+		Clock:              ts.clock,
+		Dispatch:           ts.dispatch,
+		Gophers:            gophers,
 		Incinerators:       incinerators,
+		Logger:             ts.logger,
+		Selection:          ts.selection,
+		SupplyPiles:        piles,
 	}
 
-	incineratorGroup := NewIncineratorGroup(&igParams)
-
-	for _, gopher := range gophers {
-		go pileGroup.Supply(gopher)
-		go incineratorGroup.Consume(gopher)
-	}
+	simulation := NewSimulation(ts.ctx, &simParams)
 
 	return &TestPlayers{
 		books:            books,
 		bookIds:          bookIds,
 		gophers:          gophers,
 		incinerators:     incinerators,
-		incineratorGroup: incineratorGroup,
+		incineratorGroup: simulation.IncineratorGroup(),
+		simulation:       simulation,
 		supplyPiles:      piles,
-		supplyPileGroup:  pileGroup,
+		supplyPileGroup:  simulation.SupplyPileGroup(),
 	}
 }
 
@@ -242,6 +339,7 @@ func NewDefaultTestSuite() *TestSuite {
 		burnDuration:            time.Duration(1e5),
 		burnRounds:              10,
 		contribPercentThreshold: 0.2,
+		ctx:                     context.Background(),
 		gopherCapacity:          19,
 		gopherCount:             5,
 		gopherTakeTimeout:       time.Duration(1e5),
@@ -0,0 +1,317 @@
+package goburnbooks
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Simulation represents a full run of the system, from the supply piles to the
+// incinerators.
+type Simulation interface {
+	Terminator
+
+	// Get the auditor that reconciles take results with burn results.
+	Auditor() Auditor
+
+	// This channel emits a report once every supply pile is empty and every
+	// gopher and incinerator is idle, or once the simulation has been stopped.
+	Done() <-chan Report
+	IncineratorGroup() IncineratorGroup
+	SupplyPileGroup() SupplyPileGroup
+}
+
+// SimulationParams represents all the required parameters to build a
+// Simulation. The auditor is optional, but books that never leave their piles
+// can only be found if their supplies have been declared to it beforehand.
+//
+// The dispatch strategy decides which incinerator receives each batch, as
+// described in IncineratorGroupParams, and the selection strategy decides which
+// pile each gopher takes from, as described in SupplyPileGroup. Work stealing
+// is likewise described in IncineratorGroupParams.
+//
+// Relays are earlier stages of the pipeline, whose staging piles should be
+// among the supply piles. Their take results are not audited, since a book
+// taken by a relay is taken again from the staging pile, but what their
+// staging piles drop is.
+type SimulationParams struct {
+	Auditor            Auditor
+	BurnResultCapacity uint
+	Clock              Clock
+	Dispatch           DispatchStrategy
+	Gophers            []Gopher
+	Incinerators       []FIncinerator
+	Logger             Logger
+	Relays             []Relay
+	Selection          SelectionStrategy
+	SupplyPiles        []FSupplyPile
+	WorkStealing       bool
+}
+
+type simulation struct {
+	*lifecycle
+	SimulationParams
+	doneCh           chan Report
+	incineratorGroup IncineratorGroup
+	pileClosedCh     chan interface{}
+	startTime        time.Time
+	supplyPileGroup  SupplyPileGroup
+	log              LeveledLogger
+	takeResultCh     chan SupplyTakeResult
+	watchDoneCh      chan interface{}
+}
+
+func (s *simulation) String() string {
+	return fmt.Sprintf("Simulation with %d supplies", s.supplyCount())
+}
+
+func (s *simulation) Auditor() Auditor {
+	return s.SimulationParams.Auditor
+}
+
+func (s *simulation) Done() <-chan Report {
+	return s.doneCh
+}
+
+func (s *simulation) IncineratorGroup() IncineratorGroup {
+	return s.incineratorGroup
+}
+
+func (s *simulation) SupplyPileGroup() SupplyPileGroup {
+	return s.supplyPileGroup
+}
+
+// Get the number of supplies that have been stocked so far, which only grows
+// while restockable piles are open.
+func (s *simulation) supplyCount() int {
+	count := 0
+
+	for _, pile := range s.SupplyPiles {
+		count += pile.Stocked()
+	}
+
+	return count
+}
+
+// Since piles count their supplies before supplying them, the system is idle
+// once everything that has been stocked is taken and processed, and every pile
+// is closed with nothing left. Waiting for the take results as well means the
+// audit does not miss any of them.
+func (s *simulation) isFinished(takenCount int, processedCount int) bool {
+	supplyCount := s.supplyCount()
+
+	if takenCount < supplyCount || processedCount < supplyCount {
+		return false
+	}
+
+	for _, pile := range s.SupplyPiles {
+		if !pile.Exhausted() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *simulation) report(completed bool, burnedCount int, failedCount int) Report {
+	ig := s.incineratorGroup
+	spg := s.supplyPileGroup
+	pileContrib := spg.SupplyPileContribMap()
+	takerContrib := spg.SupplyTakerContribMap()
+
+	for _, relay := range s.Relays {
+		staging := relay.Staging()
+		dropped := staging.Dropped()
+		droppedIDs := make([]string, len(dropped))
+
+		for ix, burnable := range dropped {
+			droppedIDs[ix] = burnable.BurnableID()
+		}
+
+		s.SimulationParams.Auditor.RecordDropped(staging.SupplyPileID(), droppedIDs...)
+
+		for id, count := range relay.SupplyPileGroup().SupplyPileContribMap() {
+			pileContrib[id] += count
+		}
+
+		for id, count := range relay.SupplyPileGroup().SupplyTakerContribMap() {
+			takerContrib[id] += count
+		}
+	}
+
+	return Report{
+		Completed:          completed,
+		Duration:           s.Clock.Now().Sub(s.startTime),
+		SupplyCount:        s.supplyCount(),
+		BurnedCount:        burnedCount,
+		FailedCount:        failedCount,
+		BurnedIDs:          ig.BurnedIDMap(),
+		FailedIDs:          ig.FailedIDMap(),
+		IncineratorContrib: ig.IncineratorContribMap(),
+		ProviderContrib:    ig.ProviderContribMap(),
+		SupplyPileContrib:  pileContrib,
+		SupplyTakerContrib: takerContrib,
+		SLA:                NewSLASummary(spg.Taken(), ig.Burned()),
+		Violations:         s.SimulationParams.Auditor.Reconcile().Violations(),
+	}
+}
+
+func (s *simulation) loopWatch() {
+	auditor := s.SimulationParams.Auditor
+	burnResultCh := s.incineratorGroup.BurnResultChannel()
+	logger := s.log
+	burnedCount := 0
+	failedCount := 0
+	takenCount := 0
+	defer close(s.watchDoneCh)
+
+	if s.isFinished(0, 0) {
+		s.doneCh <- s.report(true, burnedCount, failedCount)
+		return
+	}
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			s.doneCh <- s.report(false, burnedCount, failedCount)
+			return
+
+		case result, ok := <-burnResultCh:
+			if !ok {
+				s.doneCh <- s.report(false, burnedCount, failedCount)
+				return
+			}
+
+			auditor.RecordBurned(result)
+			bookField := Field(FieldBookID, result.Burned().BurnableID())
+			incField := Field(FieldPeerID, result.IncineratorID())
+
+			if err := result.Err(); err != nil {
+				logger.Warn("gave up on book", bookField, incField, Field("error", err))
+				failedCount++
+			} else {
+				logger.Debug("burned book", bookField, incField)
+				burnedCount++
+			}
+
+		case result := <-s.takeResultCh:
+			auditor.RecordTaken(result)
+			takenCount += len(result.SupplyIDs())
+
+		// A pile may be closed after everything has been burned, e.g. a staging
+		// pile whose relay is only done once the last book has been staged.
+		case <-s.pileClosedCh:
+		}
+
+		if s.isFinished(takenCount, burnedCount+failedCount) {
+			logger.Info(
+				"finished",
+				Field(FieldBookCount, burnedCount),
+				Field("failedCount", failedCount),
+			)
+			s.doneCh <- s.report(true, burnedCount, failedCount)
+			return
+		}
+	}
+}
+
+// NewSimulation creates a new Simulation, which takes ownership of the relays,
+// piles, gophers and incinerators it is given and starts the system right
+// away.
+//
+// Cancelling the context stops the simulation, in which case the emitted report
+// is not complete. Terminating the simulation terminates everything it owns.
+// The reported duration is measured on the real clock if none is specified.
+func NewSimulation(ctx context.Context, params *SimulationParams) Simulation {
+	sim := &simulation{
+		SimulationParams: *params,
+		doneCh:           make(chan Report, 1),
+		pileClosedCh:     make(chan interface{}, 1),
+		takeResultCh:     make(chan SupplyTakeResult),
+		watchDoneCh:      make(chan interface{}),
+	}
+
+	sim.Clock = clockOrDefault(sim.Clock)
+	sim.log = Leveled(sim.Logger).With(Field(FieldActor, "simulation"))
+
+	if sim.SimulationParams.Auditor == nil {
+		sim.SimulationParams.Auditor = NewAuditor(&AuditorParams{Logger: sim.Logger})
+	}
+
+	sim.startTime = sim.Clock.Now()
+	sim.supplyPileGroup = NewSelectiveSupplyPileGroup(ctx, params.Selection, params.SupplyPiles...)
+
+	sim.incineratorGroup = NewIncineratorGroup(ctx, &IncineratorGroupParams{
+		BurnResultCapacity: params.BurnResultCapacity,
+		Dispatch:           params.Dispatch,
+		Incinerators:       params.Incinerators,
+		WorkStealing:       params.WorkStealing,
+	})
+
+	sim.lifecycle = newLifecycle(ctx, nil, func() {
+		for _, relay := range sim.Relays {
+			relay.Terminate()
+		}
+
+		for _, gopher := range sim.Gophers {
+			gopher.Terminate()
+		}
+
+		sim.supplyPileGroup.Terminate()
+		sim.incineratorGroup.Terminate()
+	})
+
+	// Take results are handed to the watch loop, so that it can tell when the
+	// last of them has arrived.
+	sim.supplyPileGroup.AddTakeListener(func(result SupplyTakeResult) {
+		select {
+		case sim.takeResultCh <- result:
+		case <-sim.watchDoneCh:
+		case <-sim.ctx.Done():
+		}
+	})
+
+	destinations := make(map[string]Location, len(params.Incinerators))
+
+	for _, member := range params.Incinerators {
+		if inc, ok := member.(*incinerator); ok {
+			destinations[inc.ID] = inc.Location
+		}
+	}
+
+	for _, member := range params.Gophers {
+		if g, ok := member.(*gopher); ok {
+			g.learnDestinations(destinations)
+		}
+	}
+
+	for _, gopher := range params.Gophers {
+		sim.supplyPileGroup.Supply(gopher)
+		sim.incineratorGroup.Consume(gopher)
+	}
+
+	for _, pile := range params.SupplyPiles {
+		pile := pile
+
+		sim.spawn(func() {
+			select {
+			case <-pile.Closed():
+				select {
+				case sim.pileClosedCh <- true:
+				default:
+				}
+
+			case <-sim.ctx.Done():
+			}
+		})
+	}
+
+	// The watch loop does not start if the context is already done, in which
+	// case the incomplete report is emitted right away.
+	if !sim.spawn(sim.loopWatch) {
+		close(sim.watchDoneCh)
+		sim.doneCh <- sim.report(false, 0, 0)
+	}
+
+	return sim
+}
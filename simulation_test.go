@@ -0,0 +1,193 @@
+package goburnbooks
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test_SimulationWithoutSupplies_ShouldFinishImmediately(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	suite.supplyPerPileCount = 0
+
+	/// When
+	players := suite.SetUpSystem()
+	defer players.Terminate()
+	report, err := players.Wait(suite.waitDuration)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Completed || report.BurnedCount != 0 {
+		t.Errorf("Should have completed without burning, got %d", report.BurnedCount)
+	}
+}
+
+func Test_CancellingSimulation_ShouldReportIncomplete(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	ctx, cancel := context.WithCancel(suite.ctx)
+	suite.ctx = ctx
+	players := suite.SetUpSystem()
+	defer players.Terminate()
+
+	/// When
+	cancel()
+	report, err := players.Wait(suite.waitDuration)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Completed {
+		t.Errorf("Should not have completed after cancellation")
+	}
+
+	if report.SupplyCount != int(suite.TotalSupplyCount()) {
+		t.Errorf("Should have %d supplies, got %d", suite.TotalSupplyCount(), report.SupplyCount)
+	}
+}
+
+func Test_SimulatingWithCancelledContext_ShouldReportIncomplete(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+	suite.ctx = ctx
+
+	/// When
+	players := suite.SetUpSystem()
+	defer players.Terminate()
+	report, err := players.Wait(suite.waitDuration)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Completed || report.BurnedCount != 0 {
+		t.Errorf("Should not have completed or burned, got %d", report.BurnedCount)
+	}
+}
+
+func Test_SimulatingOnFakeClock_ShouldTimestampAllResults(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	clock := NewFakeClock(time.Unix(0, 0))
+	suite := NewDefaultTestSuite()
+	suite.clock = clock
+	players := suite.SetUpSystem()
+	defer players.Terminate()
+
+	/// When
+	_, err := players.WaitAdvancing(clock, suite.waitDuration)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taken := players.supplyPileGroup.Taken()
+	burned := players.incineratorGroup.Burned()
+	takeSequences := make(map[string]map[uint64]bool, 0)
+	burnSequences := make(map[string]map[uint64]bool, 0)
+
+	for _, result := range taken {
+		if takeSequences[result.PileID()] == nil {
+			takeSequences[result.PileID()] = make(map[uint64]bool, 0)
+		}
+
+		takeSequences[result.PileID()][result.Sequence()] = true
+
+		if result.BatchID() != fmt.Sprintf("%s-%d", result.PileID(), result.Sequence()) {
+			t.Errorf("Should have derived batch ID from sequence, got %s", result.BatchID())
+		}
+
+		if result.StartTime().IsZero() ||
+			result.QueueWait() < 0 ||
+			result.EndTime().Sub(result.StartTime()) < result.QueueWait() {
+			t.Errorf("Should have timestamped take %v", result)
+		}
+	}
+
+	for _, result := range burned {
+		if burnSequences[result.IncineratorID()] == nil {
+			burnSequences[result.IncineratorID()] = make(map[uint64]bool, 0)
+		}
+
+		burnSequences[result.IncineratorID()][result.Sequence()] = true
+
+		if result.BatchID() == "" ||
+			result.StartTime().IsZero() ||
+			result.QueueWait() < 0 ||
+			result.EndTime().Sub(result.StartTime())-result.QueueWait() < suite.burnDuration {
+			t.Errorf("Should have timestamped burn %v", result)
+		}
+	}
+
+	for id, sequences := range takeSequences {
+		for sequence := uint64(1); sequence <= uint64(len(sequences)); sequence++ {
+			if !sequences[sequence] {
+				t.Errorf("Pile %s should have numbered takes contiguously, missing %d", id, sequence)
+			}
+		}
+	}
+
+	for id, sequences := range burnSequences {
+		for sequence := uint64(1); sequence <= uint64(len(sequences)); sequence++ {
+			if !sequences[sequence] {
+				t.Errorf("Incinerator %s should have numbered burns contiguously, missing %d", id, sequence)
+			}
+		}
+	}
+
+	if latencies := EndToEndLatencies(taken, burned); len(latencies) != int(suite.TotalSupplyCount()) {
+		t.Errorf("Should have measured %d latencies, got %d", suite.TotalSupplyCount(), len(latencies))
+	}
+}
+
+func Test_ComputingEndToEndLatencies_ShouldMeasureFromLastTake(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	start := time.Unix(0, 0)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	taken := []SupplyTakeResult{
+		NewSupplyTakeResult(&SupplyTakeResultParams{EndTime: at(1), SupplyIDs: []string{"a", "b"}}),
+		NewSupplyTakeResult(&SupplyTakeResultParams{EndTime: at(4), SupplyIDs: []string{"b"}}),
+		NewSupplyTakeResult(&SupplyTakeResultParams{EndTime: at(9), SupplyIDs: []string{"c"}}),
+	}
+
+	burn := func(id string, ms int) BurnResult {
+		return NewBurnResult(&BurnResultParams{
+			Burned:  NewBook(&BookParams{ID: id}),
+			EndTime: at(ms),
+		})
+	}
+
+	/// When
+	latencies := EndToEndLatencies(taken, []BurnResult{
+		burn("a", 3),
+		burn("b", 6),
+		burn("c", 8),
+		burn("d", 8),
+	})
+
+	/// Then
+	expected := map[string]time.Duration{
+		"a": 2 * time.Millisecond,
+		"b": 2 * time.Millisecond,
+	}
+
+	if fmt.Sprint(latencies) != fmt.Sprint(expected) {
+		t.Errorf("Should have measured %v, got %v", expected, latencies)
+	}
+}
@@ -0,0 +1,196 @@
+package goburnbooks
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// StagingPile represents a supply pile that sits between two stages of a
+// pipeline. The gophers of the stage before hand it their batches the way they
+// would hand them to an incinerator, while those of the stage after take from
+// it like from any other pile.
+type StagingPile interface {
+	FSupplyPile
+
+	// Receive batches from a provider, which is signalled ready with the pile's
+	// ID once the previous batch is entirely in the pile. Since the pile holds
+	// at most its capacity, a batch that does not fit keeps the provider waiting
+	// until enough has been taken. Burnables that are not Suppliables cannot be
+	// staged and are dropped.
+	Consume(provider BurnableProvider)
+
+	// Get the Burnables that were dropped instead of staged, so that they are
+	// not lost without a trace.
+	Dropped() []Burnable
+
+	// Close the pile once every source has been closed and everything stocked
+	// in them has arrived here, so that the stage after knows when to stop.
+	// Piles added to the stage later are not taken into account.
+	CloseAfter(sources ...FSupplyPile)
+
+	// Draining a staging pile closes it as well, since nothing frees up space
+	// once it stops supplying. A batch that a provider still owes it is dropped,
+	// while what has been staged already stays in the pile to be withdrawn.
+	Drain() <-chan interface{}
+}
+
+type stagingPile struct {
+	*supplyPile
+	dropped      []Burnable
+	droppedMutex sync.Mutex
+	received     int64
+	receivedCh   chan interface{}
+}
+
+func (sp *stagingPile) Dropped() []Burnable {
+	sp.droppedMutex.Lock()
+	defer sp.droppedMutex.Unlock()
+	return append([]Burnable{}, sp.dropped...)
+}
+
+func (sp *stagingPile) drop(burnables ...Burnable) {
+	sp.droppedMutex.Lock()
+	defer sp.droppedMutex.Unlock()
+	sp.dropped = append(sp.dropped, burnables...)
+}
+
+func (sp *stagingPile) Consume(provider BurnableProvider) {
+	sp.spawn(func() {
+		drainingCh := sp.drainingCh
+		logger := sp.log.With(Field(FieldPeerID, provider.BurnableProviderID()))
+		provideReadyCh := provider.ReceiveProvideReadyChannel()
+		var provideCh <-chan []Burnable
+		var draining bool
+
+		for {
+			select {
+			case <-sp.ctx.Done():
+				return
+
+			// A provider that has received a ready signal will hand over its next
+			// batch to no one else, so wait for that batch before leaving.
+			case <-drainingCh:
+				if provideCh == nil {
+					return
+				}
+
+				drainingCh = nil
+				draining = true
+
+			case provideReadyCh <- sp.ID:
+				provideReadyCh = nil
+				provideCh = provider.SendBurnablesChannel()
+
+			case burnables := <-provideCh:
+				logger.Debug(
+					"received batch",
+					Field(FieldBatchID, supplyBatchIDs(burnables)),
+					Field(FieldBookCount, len(burnables)),
+				)
+				provideCh = nil
+				sp.stage(logger, burnables)
+
+				if draining {
+					return
+				}
+
+				provideReadyCh = provider.ReceiveProvideReadyChannel()
+			}
+		}
+	})
+}
+
+func (sp *stagingPile) Drain() <-chan interface{} {
+	drainedCh := sp.supplyPile.Drain()
+	sp.Close()
+	return drainedCh
+}
+
+// Restock the pile with a batch, blocking while it is full. Whatever cannot be
+// staged is dropped.
+func (sp *stagingPile) stage(logger LeveledLogger, burnables []Burnable) {
+	for ix, burnable := range burnables {
+		supply, ok := burnable.(Suppliable)
+
+		if !ok {
+			logger.Warn("cannot stage, dropping", Field(FieldBookID, burnable.BurnableID()))
+			sp.drop(burnable)
+			continue
+		}
+
+		if err := sp.Restock(supply); err != nil {
+			logger.Warn(
+				"could not stage batch, dropping the rest",
+				Field(FieldBookCount, len(burnables)-ix),
+				Field("error", err),
+			)
+
+			sp.drop(burnables[ix:]...)
+			break
+		}
+	}
+
+	atomic.AddInt64(&sp.received, int64(len(burnables)))
+
+	select {
+	case sp.receivedCh <- true:
+	default:
+	}
+}
+
+func (sp *stagingPile) CloseAfter(sources ...FSupplyPile) {
+	sp.spawn(func() {
+		for _, source := range sources {
+			select {
+			case <-source.Closed():
+			case <-sp.drainingCh:
+				return
+			case <-sp.ctx.Done():
+				return
+			}
+		}
+
+		for {
+			expected := int64(0)
+			exhausted := true
+
+			for _, source := range sources {
+				expected += int64(source.Stocked())
+				exhausted = exhausted && source.Exhausted()
+			}
+
+			if exhausted && atomic.LoadInt64(&sp.received) >= expected {
+				sp.log.Info("sources are done, closing", Field(FieldBookCount, expected))
+				sp.Close()
+				return
+			}
+
+			select {
+			case <-sp.receivedCh:
+			case <-sp.drainingCh:
+				return
+			case <-sp.ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// NewStagingPile creates a new StagingPile, which is restockable and holds at
+// most its capacity (one if it is not specified, or the number of initial
+// supplies if that is larger). Cancelling the context stops the pile from
+// supplying as well as consuming.
+func NewStagingPile(ctx context.Context, params *SupplyPileParams) StagingPile {
+	restockable := *params
+	restockable.Restockable = true
+
+	if restockable.Capacity == 0 {
+		restockable.Capacity = 1
+	}
+
+	return &stagingPile{
+		supplyPile: newSupplyPile(ctx, &restockable),
+		receivedCh: make(chan interface{}, 1),
+	}
+}
@@ -0,0 +1,190 @@
+package goburnbooks
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_StagingPileBeingFull_ShouldKeepProviderWaiting(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	sourceCh := make(chan []Burnable)
+	staging := NewStagingPile(suite.ctx, &SupplyPileParams{Capacity: 2, ID: "staging"})
+	defer staging.Terminate()
+
+	provider := NewBurnableProvider(suite.ctx, &BurnableProviderParams{
+		BurnableProviderRawParams: BurnableProviderRawParams{BPID: "0"},
+		ReceiveBurnableSourceCh:   sourceCh,
+	})
+
+	defer provider.Terminate()
+	batch := make([]Burnable, 0)
+
+	for ix := 0; ix < 4; ix++ {
+		batch = append(batch, NewBook(&BookParams{ID: fmt.Sprintf("%d", ix)}))
+	}
+
+	send := func(burnables []Burnable) bool {
+		select {
+		case sourceCh <- burnables:
+			return true
+
+		case <-time.After(suite.waitDuration / 50):
+			return false
+		}
+	}
+
+	/// When
+	staging.Consume(provider)
+	firstSent := send(batch[:3])
+	secondSentWhileFull := send(batch[3:])
+	withdrawn := staging.Withdraw()
+	secondSent := send(batch[3:])
+
+	/// Then
+	if !firstSent || secondSentWhileFull {
+		t.Errorf("Should only have accepted another batch once there was space")
+	}
+
+	if len(withdrawn) != 2 || !secondSent {
+		t.Errorf("Should have held 2 and taken the next batch, got %d", len(withdrawn))
+	}
+
+	if staging.Remaining() > 2 {
+		t.Errorf("Should not have held more than its capacity, got %d", staging.Remaining())
+	}
+}
+
+func Test_DrainingPartlyFullStagingPile_ShouldReturnDroppedBatch(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	staging := NewStagingPile(suite.ctx, &SupplyPileParams{Capacity: 4, ID: "staging"})
+	defer staging.Terminate()
+	provider := &stubProvider{burnablesCh: make(chan []Burnable), readyCh: make(chan string)}
+	var burning, peak int64
+
+	// This one is a Burnable, but not a Suppliable.
+	unstageable := &trackedBurnable{Burnable: NewBook(&BookParams{ID: "2"}), burning: &burning, peak: &peak}
+
+	/// When
+	staging.Consume(provider)
+	<-provider.readyCh
+	provider.burnablesCh <- []Burnable{NewBook(&BookParams{ID: "0"})}
+	<-provider.readyCh
+	drainedCh := staging.Drain()
+	provider.burnablesCh <- []Burnable{NewBook(&BookParams{ID: "1"}), unstageable}
+
+	select {
+	case <-drainedCh:
+	case <-time.After(suite.waitDuration):
+		t.Fatal("Should have drained")
+	}
+
+	/// Then
+	dropped := make([]string, 0)
+
+	for _, burnable := range staging.Dropped() {
+		dropped = append(dropped, burnable.BurnableID())
+	}
+
+	if expected := []string{"1", "2"}; !reflect.DeepEqual(dropped, expected) {
+		t.Errorf("Should have dropped %v, got %v", expected, dropped)
+	}
+
+	if withdrawn := staging.Withdraw(); len(withdrawn) != 1 || withdrawn[0].SuppliableID() != "0" {
+		t.Errorf("Should have kept what was staged before draining, got %v", withdrawn)
+	}
+}
+
+func Test_RelayingThroughStagingPile_ShouldBurnAllOnce(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	clock := NewFakeClock(time.Unix(0, 0))
+	suite := NewDefaultTestSuite()
+	suite.clock = clock
+	suite.supplyPerPileCount = 200
+
+	// Idle gophers poll the staging pile until their take times out, and the
+	// fake clock waits for them to settle on every poll, so poll less often.
+	suite.gopherTakeTimeout = suite.tripDelay / 10
+	suite.supplyPileTimeout = suite.tripDelay / 10
+	piles, books, _ := suite.SupplyPiles()
+	relayGophers := make([]Gopher, 0)
+
+	staging := NewStagingPile(suite.ctx, &SupplyPileParams{
+		Capacity:    suite.gopherCapacity * 2,
+		Clock:       clock,
+		ID:          "staging",
+		Logger:      suite.logger,
+		TakeTimeout: suite.supplyPileTimeout,
+	})
+
+	for ix := 0; ix < int(suite.gopherCount); ix++ {
+		id := fmt.Sprintf("relay-%d", ix)
+
+		relayGophers = append(relayGophers, NewGopher(suite.ctx, &GopherParams{
+			BurnableProviderRawParams: BurnableProviderRawParams{BPID: id},
+			SupplyTakerRawParams: SupplyTakerRawParams{
+				Cap:         suite.gopherCapacity,
+				STID:        id,
+				TakeTimeout: suite.gopherTakeTimeout,
+			},
+			Clock:        clock,
+			Logger:       suite.logger,
+			TripDuration: suite.tripDelay,
+		}))
+	}
+
+	auditor := NewAuditor(&AuditorParams{Logger: suite.logger})
+
+	for ix, book := range books {
+		auditor.RecordSupply(piles[ix/int(suite.supplyPerPileCount)].SupplyPileID(), book.SuppliableID())
+	}
+
+	relay := NewRelay(suite.ctx, &RelayParams{
+		Gophers:     relayGophers,
+		Staging:     staging,
+		SupplyPiles: piles,
+	})
+
+	simulation := NewSimulation(suite.ctx, &SimulationParams{
+		Auditor:      auditor,
+		Clock:        clock,
+		Gophers:      suite.Gophers(),
+		Incinerators: suite.Incinerators(),
+		Logger:       suite.logger,
+		Relays:       []Relay{relay},
+		SupplyPiles:  []FSupplyPile{staging},
+	})
+
+	defer simulation.Terminate()
+	players := &TestPlayers{simulation: simulation}
+
+	/// When
+	report, err := players.WaitAdvancing(clock, suite.waitDuration)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Completed || report.BurnedCount != len(books) || len(report.Violations) > 0 {
+		t.Errorf("Should have burned %d once, got %d: %v", len(books), report.BurnedCount, report.Violations)
+	}
+
+	if report.SupplyPileContrib["staging"] != len(books) {
+		t.Errorf("Should have taken everything from the staging pile, got %v", report.SupplyPileContrib)
+	}
+
+	if totalContribCount(relay.SupplyPileGroup().SupplyPileContribMap()) != len(books) {
+		t.Errorf("Should have relayed everything, got %v", report.SupplyPileContrib)
+	}
+
+	if !staging.Exhausted() {
+		t.Errorf("Staging pile should have been closed once everything was relayed")
+	}
+}
@@ -0,0 +1,126 @@
+package goburnbooks
+
+import (
+	"context"
+	"sync"
+)
+
+// SupplyProducer produces Suppliables one at a time, and returns false once it
+// has nothing more to produce. It should also return false once the context is
+// done, which happens when the pile it produces for drains or terminates.
+type SupplyProducer func(ctx context.Context) (Suppliable, bool)
+
+// ProduceFromChannel returns a SupplyProducer that receives Suppliables from a
+// channel until it is closed.
+func ProduceFromChannel(supplyCh <-chan Suppliable) SupplyProducer {
+	return func(ctx context.Context) (Suppliable, bool) {
+		select {
+		case supply, ok := <-supplyCh:
+			return supply, ok
+
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// ProduceFromIterator returns a SupplyProducer that calls an iterator until it
+// returns false, e.g. a generator or a cursor over a file. The iterator itself
+// knows nothing of the context, so it is no longer called once the context is
+// done, but a call in progress must return on its own.
+func ProduceFromIterator(next func() (Suppliable, bool)) SupplyProducer {
+	return func(ctx context.Context) (Suppliable, bool) {
+		if ctx.Err() != nil {
+			return nil, false
+		}
+
+		return next()
+	}
+}
+
+// StreamingSupplyPile represents a SupplyPile that is restocked lazily from a
+// SupplyProducer.
+type StreamingSupplyPile interface {
+	FSupplyPile
+
+	// Get the Suppliables that were produced, but could not be restocked since
+	// the pile had been closed, drained or terminated in the meantime. As the
+	// producer is not called again after that, there is at most one.
+	Dropped() []Suppliable
+}
+
+type streamingSupplyPile struct {
+	*supplyPile
+	dropped      []Suppliable
+	droppedMutex sync.Mutex
+}
+
+func (ssp *streamingSupplyPile) Dropped() []Suppliable {
+	ssp.droppedMutex.Lock()
+	defer ssp.droppedMutex.Unlock()
+	return append([]Suppliable{}, ssp.dropped...)
+}
+
+// Keep restocking from the producer until it runs out, or the pile stops
+// taking more.
+func (ssp *streamingSupplyPile) produce(producer SupplyProducer) {
+	ctx, cancel := context.WithCancel(ssp.ctx)
+	defer cancel()
+
+	ssp.fork(func() {
+		select {
+		case <-ssp.drainingCh:
+			cancel()
+
+		case <-ctx.Done():
+		}
+	})
+
+	for {
+		supply, ok := producer(ctx)
+
+		if !ok {
+			if ctx.Err() == nil {
+				ssp.log.Info("producer ran out, closing", Field(FieldBookCount, ssp.Stocked()))
+				ssp.Close()
+			}
+
+			return
+		}
+
+		if err := ssp.restock(ctx, supply); err != nil {
+			ssp.log.Warn(
+				"dropped supply, stopped producing",
+				Field(FieldBookID, supply.SuppliableID()),
+				Field("error", err),
+			)
+
+			ssp.droppedMutex.Lock()
+			ssp.dropped = append(ssp.dropped, supply)
+			ssp.droppedMutex.Unlock()
+			return
+		}
+	}
+}
+
+// NewStreamingSupplyPile creates a new SupplyPile that is restocked lazily from
+// a SupplyProducer, which makes it suitable for books streamed from a file, a
+// database or a generator. The pile holds at most its capacity (one if it is
+// not specified, or the number of initial supplies if that is larger), so
+// memory stays flat however many Suppliables are produced. The pile is closed
+// once the producer runs out.
+//
+// The producer is called from a goroutine that the pile waits for when it
+// drains or terminates, so it must return once its context is done. It is not
+// called again once the pile has been closed, drained or terminated.
+func NewStreamingSupplyPile(
+	ctx context.Context,
+	params *SupplyPileParams,
+	producer SupplyProducer,
+) StreamingSupplyPile {
+	restockable := *params
+	restockable.Restockable = true
+	pile := &streamingSupplyPile{supplyPile: newSupplyPile(ctx, &restockable)}
+	pile.spawn(func() { pile.produce(producer) })
+	return pile
+}
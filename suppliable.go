@@ -4,3 +4,14 @@ package goburnbooks
 type Suppliable interface {
 	SuppliableID() string
 }
+
+// Get the ids of a number of Suppliables.
+func supplyIDs(supplies []Suppliable) []string {
+	ids := make([]string, len(supplies))
+
+	for ix, supply := range supplies {
+		ids[ix] = supply.SuppliableID()
+	}
+
+	return ids
+}
@@ -1,11 +1,23 @@
 package goburnbooks
 
 import (
+	"container/heap"
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrPileFull is returned when a SupplyPile has no space left for a Suppliable.
+var ErrPileFull = errors.New("supply pile is full")
+
+// ErrPileClosed is returned when restocking or depositing into a SupplyPile
+// that has been closed, or that is not restockable.
+var ErrPileClosed = errors.New("supply pile is closed")
+
 // SupplyPile represents a pile of Suppliables.
 type SupplyPile interface {
 	Supply(taker SupplyTaker)
@@ -14,6 +26,49 @@ type SupplyPile interface {
 // FSupplyPile represents a SupplyPile that has all functionalities.
 type FSupplyPile interface {
 	SupplyPile
+	Terminator
+
+	// Draining stops the pile from accepting ready signals, while supplies that
+	// have already been loaded are still handed over. If the taker does not
+	// accept them within the take timeout, they are put back into the pile.
+	Drainer
+
+	// Closing marks the pile as finished, so that it can no longer be restocked.
+	// Once a closed pile has nothing left, it stops answering ready signals so
+	// that takers look elsewhere.
+	Close()
+
+	// This channel is closed once the pile has been closed.
+	Closed() <-chan interface{}
+
+	// Put Suppliables into the pile without blocking, in order, until one does
+	// not fit, in which case ErrPileFull is returned. Like restocking, this
+	// returns ErrPileClosed once the pile has been closed, since what is
+	// deposited after a closed pile has run out would never be supplied.
+	Deposit(supplies ...Suppliable) error
+
+	// Check whether the pile has been closed and has nothing left.
+	Exhausted() bool
+
+	// Get the number of Suppliables that have not left the pile.
+	Remaining() int
+
+	// Put Suppliables into the pile in order, blocking while it is full. This
+	// fails with ErrPileClosed if the pile is closed while waiting, or with the
+	// context's error if it terminates, in which case the Suppliables before the
+	// one being waited on have been restocked already.
+	Restock(supplies ...Suppliable) error
+
+	// Get the number of Suppliables the pile started out with or has been
+	// restocked with so far.
+	Stocked() int
+	SupplyPileID() string
+
+	// Take every Suppliable that is in the pile out of it. Only do this once the
+	// pile has been drained, since it would compete with supplying otherwise.
+	Withdraw() []Suppliable
+
+	// This channel is closed once the pile has terminated.
 	TakeResultChannel() <-chan SupplyTakeResult
 }
 
@@ -23,19 +78,51 @@ type FSupplyPile interface {
 // frequently if there are many piles with small supply count). It should not
 // be 0, however, because that will randomize the select sequence so much so
 // that loading becomes suboptimal.
+//
+// The capacity is the maximum number of Suppliables the pile can hold, which
+// defaults to the number of initial supplies, or 1 for a restockable pile that
+// has none. A restockable pile stays open for restocking until it is closed,
+// while any other pile is closed from the start.
+//
+// Suppliables that are Prioritized are supplied highest priority first, and
+// those of equal priority earliest deadline first if they are Deadlined, then
+// in the order they entered the pile. So that those of low priority do not
+// starve while a restockable pile keeps receiving higher ones, a queued
+// Suppliable gains one priority level for every aging interval it has waited,
+// if there is one. Every load the pile hands over carries its location, so
+// that the taker knows where it sets out from.
 type SupplyPileParams struct {
+	Capacity           uint
+	Clock              Clock
+	Location           Location
 	Logger             Logger
+	Metrics            Metrics
+	PriorityAging      time.Duration
+	Restockable        bool
 	Supply             []Suppliable
 	ID                 string
 	TakeResultCapacity uint
 	TakeTimeout        time.Duration
 }
 
+// Every Suppliable in the pile has a token in the supply channel, which lets
+// the supply loops wait for supplies, and every free space has a token in the
+// slot channel, which bounds the pile's capacity. The Suppliables themselves
+// are queued by priority and deadline.
 type supplyPile struct {
-	mutex sync.Mutex
+	*lifecycle
 	SupplyPileParams
-	supplyCh     chan Suppliable
-	takeResultCh chan SupplyTakeResult
+	closedCh      chan interface{}
+	closeOnce     sync.Once
+	log           LeveledLogger
+	queue         supplyQueue
+	queueMutex    sync.Mutex
+	queueSequence uint64
+	slotCh        chan interface{}
+	stocked       int64
+	supplyCh      chan interface{}
+	takeCount     uint64
+	takeResultCh  chan SupplyTakeResult
 }
 
 func (sp *supplyPile) String() string {
@@ -43,19 +130,30 @@ func (sp *supplyPile) String() string {
 }
 
 func (sp *supplyPile) Supply(taker SupplyTaker) {
-	go func() {
+	sp.spawn(func() {
 		capacity := taker.Capacity()
+		ctx := sp.ctx
+		drainingCh := sp.drainingCh
 		loaded := make([]Suppliable, 0)
-		logger := sp.Logger
+		loadedWeight := uint(0)
 		readyCh := taker.SendTakeReadyChannel()
 		takerID := taker.SupplyTakerID()
+		logger := sp.log.With(Field(FieldPeerID, takerID))
+		var batchID string
+		var draining bool
+		var giveBackCh <-chan time.Time
+		var loadedAt time.Time
 		var loadResult SupplyTakeResult
-		var loadSupplyCh chan<- []Suppliable
+		var loadSupplyCh chan<- SupplyLoad
+		var pendingReadyCh <-chan interface{}
 		var resetSequenceCh chan interface{}
+		var sequence uint64
 		var startLoadCh chan<- interface{}
-		var supplyCh chan Suppliable
+		var startedAt time.Time
+		var supplyCh chan interface{}
 		var supplyTimeoutCh <-chan time.Time
 		var takeResultCh chan SupplyTakeResult
+		closedCh := sp.closedCh
 
 		for {
 			// The sequence of operation here is:
@@ -73,24 +171,102 @@ func (sp *supplyPile) Supply(taker SupplyTaker) {
 			// - After the result has been deposited, initialize the reset channel.
 			// - Finally, reset the ready channel and the loaded slice to prepare for
 			// another loading process
+			//
+			// When the pile is draining, the sequence is cut short wherever possible
+			// and ends instead of resetting.
 			select {
+			case <-ctx.Done():
+				return
+
+			case <-drainingCh:
+				drainingCh = nil
+				draining = true
+
+				switch {
+				case readyCh != nil:
+					return
+
+				case supplyCh != nil:
+					logger.Debug("draining, loading what it has")
+					supplyCh = nil
+					supplyTimeoutCh = nil
+					startLoadCh = make(chan interface{}, 1)
+
+				case loadSupplyCh != nil:
+					giveBackCh = sp.Clock.After(sp.TakeTimeout)
+				}
+
+			// Only stop while waiting for the taker to be ready, since the sequence
+			// checks again once it has been reset.
+			case <-closedCh:
+				closedCh = nil
+
+				if readyCh != nil && sp.Exhausted() {
+					logger.Debug("closed and exhausted, no longer supplying")
+					return
+				}
+
+			case <-giveBackCh:
+				logger.Debug("taker did not take, putting back", Field(FieldBookCount, len(loaded)))
+
+				// A restockable pile may have been refilled in the meantime, in which
+				// case this waits for space until the pile terminates.
+				for _, supply := range loaded {
+					select {
+					case <-sp.slotCh:
+						sp.put(supply)
+
+					case <-ctx.Done():
+						return
+					}
+				}
+
+				return
+
+			// A taker that timed out before the load was handed over only listens
+			// for it again after its next ready signal has been accepted, which no
+			// other pile may be around to do.
+			case <-pendingReadyCh:
+				logger.Debug("received ready while holding load")
+
 			case <-readyCh:
 				// Nullify the ready channel here to let the sequence run in peace.
-				logger.Printf("%v: received ready from %v", sp, taker)
+				logger.Debug("received ready")
 				readyCh = nil
+				startedAt = sp.Clock.Now()
 				supplyCh = sp.supplyCh
-				supplyTimeoutCh = time.After(sp.TakeTimeout)
+				supplyTimeoutCh = sp.Clock.After(sp.TakeTimeout)
 
 			// >>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>
-			case supply, ok := <-supplyCh:
-				if !ok {
-					return
-				}
+			// Loading stops as soon as the next Suppliable does not fit, so that
+			// the taker does not wait for the timeout with room it cannot fill.
+			// Everything at hand is loaded at once rather than racing the timeout
+			// for every Suppliable, which a busy scheduler may otherwise let win
+			// long before the pile runs out.
+			case <-supplyCh:
+				full := false
 
-				loaded = append(loaded, supply)
+				for available := true; available; {
+					supply, fits := sp.takeFitting(loadedWeight, capacity)
 
-				if uint(len(loaded)) == capacity {
-					logger.Printf("%v: supplied to full (%d) for %v", sp, capacity, taker)
+					if fits {
+						loaded = append(loaded, supply)
+						loadedWeight += weightOf(supply)
+					}
+
+					if full = !fits || (capacity > 0 && loadedWeight >= capacity); full {
+						break
+					}
+
+					select {
+					case <-sp.supplyCh:
+					default:
+						available = false
+					}
+				}
+
+				if full {
+					logger.Debug("supplied to full", Field(FieldBookCount, len(loaded)))
 					supplyCh = nil
 					supplyTimeoutCh = nil
 
@@ -100,7 +276,7 @@ func (sp *supplyPile) Supply(taker SupplyTaker) {
 				}
 
 			case <-supplyTimeoutCh:
-				logger.Printf("%v: timed out for %v!", sp, taker)
+				logger.Debug("timed out")
 				supplyTimeoutCh = nil
 				supplyCh = nil
 				startLoadCh = make(chan interface{}, 1)
@@ -114,30 +290,48 @@ func (sp *supplyPile) Supply(taker SupplyTaker) {
 				// - The timeout channel will not emit an element fast enough to be
 				// selected.
 				startLoadCh = nil
+				loadedAt = sp.Clock.Now()
 
 				if len(loaded) > 0 {
+					sequence = atomic.AddUint64(&sp.takeCount, 1)
+					batchID = fmt.Sprintf("%s-%d", sp.ID, sequence)
+					stampSupplyBatch(batchID, loaded...)
+
 					// Only initialize the load supply channel when there are loaded items.
 					// Beware that if the taker relies on this channel to orchestrate
 					// its work, said taker should have some mechanism to detect lack of
 					// signal in order to send its requests elsewhere, such as timeout.
 					loadSupplyCh = taker.ReceiveLoadChannel()
+					pendingReadyCh = taker.SendTakeReadyChannel()
+
+					if draining {
+						giveBackCh = sp.Clock.After(sp.TakeTimeout)
+					}
 				} else {
-					logger.Printf("%v: did not supply anything for %v", sp, taker)
+					logger.Debug("did not supply anything")
 					resetSequenceCh = make(chan interface{}, 1)
 				}
 
-			case loadSupplyCh <- loaded:
-				logger.Printf("%v: supplied %d to %v", sp, len(loaded), taker)
+			case loadSupplyCh <- SupplyLoad{Origin: sp.Location, PileID: sp.ID, Supplies: loaded}:
+				logger.Debug("supplied", Field(FieldBatchID, batchID), Field(FieldBookCount, len(loaded)))
+				sp.Metrics.ObserveSupplied(sp.ID, len(loaded))
+				giveBackCh = nil
 				loadSupplyCh = nil
+				pendingReadyCh = nil
 				takeResultCh = sp.takeResultCh
+				takenAt := sp.Clock.Now()
 
-				supplyIds := make([]string, len(loaded))
-
-				for ix, supply := range loaded {
-					supplyIds[ix] = supply.SuppliableID()
-				}
-
-				loadResult = NewTakeResult(sp.ID, takerID, supplyIds)
+				loadResult = NewSupplyTakeResult(&SupplyTakeResultParams{
+					BatchID:   batchID,
+					EndTime:   takenAt,
+					Metadata:  suppliesMetadata(loaded),
+					PileID:    sp.ID,
+					QueueWait: takenAt.Sub(loadedAt),
+					Sequence:  sequence,
+					StartTime: startedAt,
+					SupplyIDs: supplyIDs(loaded),
+					TakerID:   takerID,
+				})
 
 			case takeResultCh <- loadResult:
 				takeResultCh = nil
@@ -145,32 +339,269 @@ func (sp *supplyPile) Supply(taker SupplyTaker) {
 				resetSequenceCh = make(chan interface{}, 1)
 
 			case resetSequenceCh <- true:
+				if draining || sp.Exhausted() {
+					return
+				}
+
 				resetSequenceCh = nil
 				loaded = make([]Suppliable, 0)
+				loadedWeight = 0
 				readyCh = taker.SendTakeReadyChannel()
 			}
 		}
-	}()
+	})
+}
+
+// A Suppliable that remembers the batch it was last supplied in, so that the
+// incinerator that receives it can log the same batch ID as the pile did.
+type batchStamped interface {
+	supplyBatchID() string
+	stampSupplyBatch(batchID string)
+}
+
+func stampSupplyBatch(batchID string, supplies ...Suppliable) {
+	for _, supply := range supplies {
+		if stamped, ok := supply.(batchStamped); ok {
+			stamped.stampSupplyBatch(batchID)
+		}
+	}
+}
+
+// Get the distinct batches a number of Burnables were last supplied in, in the
+// order they first appear.
+func supplyBatchIDs(burnables []Burnable) string {
+	batchIDs := make([]string, 0)
+	seen := make(map[string]bool, 0)
+
+	for _, burnable := range burnables {
+		if stamped, ok := burnable.(batchStamped); ok {
+			if batchID := stamped.supplyBatchID(); batchID != "" && !seen[batchID] {
+				seen[batchID] = true
+				batchIDs = append(batchIDs, batchID)
+			}
+		}
+	}
+
+	return strings.Join(batchIDs, ",")
+}
+
+// Put a Suppliable into the pile, once a slot has been taken for it.
+func (sp *supplyPile) put(supply Suppliable) {
+	sp.queueMutex.Lock()
+	sp.queueSequence++
+
+	heap.Push(&sp.queue, &queuedSupply{
+		deadline: deadlineOf(supply),
+		priority: priorityOf(supply),
+		queuedAt: sp.Clock.Now(),
+		sequence: sp.queueSequence,
+		supply:   supply,
+	})
+
+	sp.queueMutex.Unlock()
+	sp.supplyCh <- true
+}
+
+// Take the Suppliable that comes first out of the pile, once a token has been
+// received for it, and free up its slot.
+func (sp *supplyPile) take() Suppliable {
+	sp.queueMutex.Lock()
+	sp.queue.age(sp.Clock.Now())
+	queued := heap.Pop(&sp.queue).(*queuedSupply)
+	sp.queueMutex.Unlock()
+	sp.slotCh <- true
+	return queued.supply
+}
+
+// Take the Suppliable that comes first out of the pile like take, but only if
+// it fits into a load of some weight. Otherwise, the token goes back for others
+// to take.
+func (sp *supplyPile) takeFitting(loaded uint, capacity uint) (Suppliable, bool) {
+	sp.queueMutex.Lock()
+	sp.queue.age(sp.Clock.Now())
+
+	if !fitsWeight(loaded, weightOf(sp.queue.first().supply), capacity) {
+		sp.queueMutex.Unlock()
+		sp.supplyCh <- true
+		return nil, false
+	}
+
+	queued := heap.Pop(&sp.queue).(*queuedSupply)
+	sp.queueMutex.Unlock()
+	sp.slotCh <- true
+	return queued.supply, true
+}
+
+// Put Suppliables into the pile without blocking, as long as there is space and
+// the pile has not been closed.
+func (sp *supplyPile) deposit(supplies ...Suppliable) error {
+	for _, supply := range supplies {
+		select {
+		case <-sp.closedCh:
+			return ErrPileClosed
+
+		default:
+		}
+
+		select {
+		case <-sp.slotCh:
+			sp.put(supply)
+
+		default:
+			return ErrPileFull
+		}
+	}
+
+	return nil
+}
+
+func (sp *supplyPile) Close() {
+	sp.closeOnce.Do(func() { close(sp.closedCh) })
+}
+
+func (sp *supplyPile) Closed() <-chan interface{} {
+	return sp.closedCh
+}
+
+func (sp *supplyPile) Deposit(supplies ...Suppliable) error {
+	return sp.deposit(supplies...)
+}
+
+func (sp *supplyPile) Exhausted() bool {
+	select {
+	case <-sp.closedCh:
+		return len(sp.supplyCh) == 0
+
+	default:
+		return false
+	}
+}
+
+func (sp *supplyPile) Restock(supplies ...Suppliable) error {
+	return sp.restock(sp.ctx, supplies...)
+}
+
+// Count a Suppliable as stocked before it enters the pile, so that it is never
+// taken without having been counted, and uncount it if it does not enter. Stop
+// waiting for space once the context is done, which is either the pile's own
+// or derived from it.
+func (sp *supplyPile) restock(ctx context.Context, supplies ...Suppliable) error {
+	for _, supply := range supplies {
+		select {
+		case <-sp.closedCh:
+			return ErrPileClosed
+
+		default:
+		}
+
+		atomic.AddInt64(&sp.stocked, 1)
+
+		select {
+		case <-sp.slotCh:
+			sp.put(supply)
+			continue
+
+		case <-sp.closedCh:
+			atomic.AddInt64(&sp.stocked, -1)
+			return ErrPileClosed
+
+		case <-ctx.Done():
+			atomic.AddInt64(&sp.stocked, -1)
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (sp *supplyPile) Stocked() int {
+	return int(atomic.LoadInt64(&sp.stocked))
+}
+
+func (sp *supplyPile) Drain() <-chan interface{} {
+	return sp.drain()
+}
+
+func (sp *supplyPile) Remaining() int {
+	return len(sp.supplyCh)
+}
+
+func (sp *supplyPile) SupplyPileID() string {
+	return sp.ID
 }
 
 func (sp *supplyPile) TakeResultChannel() <-chan SupplyTakeResult {
 	return sp.takeResultCh
 }
 
-// NewSupplyPile creates a new SupplyPile.
-func NewSupplyPile(params *SupplyPileParams) FSupplyPile {
+// Only take what is in the pile when called, since a blocked restock would
+// otherwise keep refilling it.
+func (sp *supplyPile) Withdraw() []Suppliable {
+	withdrawn := make([]Suppliable, 0)
+
+	for count := len(sp.supplyCh); count > 0; count-- {
+		select {
+		case <-sp.supplyCh:
+			withdrawn = append(withdrawn, sp.take())
+
+		default:
+			return withdrawn
+		}
+	}
+
+	return withdrawn
+}
+
+// NewSupplyPile creates a new SupplyPile. Cancelling the context stops the pile
+// from supplying, and closes the take result channel. The pile times out on the
+// real clock if none is specified.
+func NewSupplyPile(ctx context.Context, params *SupplyPileParams) FSupplyPile {
+	return newSupplyPile(ctx, params)
+}
+
+func newSupplyPile(ctx context.Context, params *SupplyPileParams) *supplyPile {
 	supplies := params.Supply
-	supplyCh := make(chan Suppliable, len(supplies))
+	capacity := params.Capacity
 
-	for _, supply := range supplies {
-		supplyCh <- supply
+	if capacity < uint(len(supplies)) {
+		capacity = uint(len(supplies))
 	}
 
+	// Otherwise nothing could ever be restocked.
+	if capacity == 0 && params.Restockable {
+		capacity = 1
+	}
+
+	takeResultCh := make(chan SupplyTakeResult, params.TakeResultCapacity)
+
 	pile := &supplyPile{
+		lifecycle:        newLifecycle(ctx, nil, func() { close(takeResultCh) }),
 		SupplyPileParams: *params,
-		supplyCh:         supplyCh,
-		takeResultCh:     make(chan SupplyTakeResult, params.TakeResultCapacity),
+		closedCh:         make(chan interface{}),
+		queue:            supplyQueue{aging: params.PriorityAging, supplies: make([]*queuedSupply, 0, capacity)},
+		slotCh:           make(chan interface{}, capacity),
+		stocked:          int64(len(supplies)),
+		supplyCh:         make(chan interface{}, capacity),
+		takeResultCh:     takeResultCh,
+	}
+
+	pile.Clock = clockOrDefault(pile.Clock)
+
+	for ix := uint(0); ix < capacity; ix++ {
+		pile.slotCh <- true
+	}
+
+	for _, supply := range supplies {
+		<-pile.slotCh
+		pile.put(supply)
+	}
+
+	if !pile.Restockable {
+		pile.Close()
 	}
 
+	pile.Metrics = metricsOrDefault(pile.Metrics)
+	pile.log = Leveled(pile.Logger).With(Field(FieldActor, "pile"), Field(FieldActorID, pile.ID))
+
 	return pile
 }
@@ -1,29 +1,256 @@
 package goburnbooks
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
 )
 
-// SupplyPileGroup represents a group of SupplyPiles.
+var (
+	// ErrDuplicatePile is returned when adding a pile to a group that already
+	// has one with the same ID.
+	ErrDuplicatePile = errors.New("supply pile is already in the group")
+
+	// ErrUnknownPile is returned when a pile that is not in the group is
+	// specified.
+	ErrUnknownPile = errors.New("supply pile is not in the group")
+)
+
+// SupplyPileGroup represents a group of SupplyPiles, whose membership may change
+// while it is running. The group remembers every SupplyTaker it supplies, so
+// that piles added later supply them as well.
+//
+// If the group has a selection strategy, it routes each ready signal of a
+// taker to exactly one pile picked by said strategy. Otherwise, the piles race
+// for every ready signal, and those that lose wait until their take timeout.
 type SupplyPileGroup interface {
 	SupplyPile
+	Terminator
+
+	// Add a pile, which starts supplying every taker that the group supplies.
+	AddPile(pile FSupplyPile) error
+
+	// Remove a pile by its ID. The pile is drained, so that it stops supplying
+	// while loads that are already underway are handed over, and terminated
+	// once its take results have been recorded. This blocks until then.
+	//
+	// If a destination pile in the group is specified, the Suppliables left in
+	// the removed pile are moved there. Those that are not moved, either because
+	// there is no destination, or because it is full or closed, are returned,
+	// along with ErrPileFull or ErrPileClosed in the latter cases. Since only
+	// restockable piles are open, only they can be destinations.
+	RemovePile(id string, destinationID string) ([]Suppliable, error)
+
+	// Register a function to be called with every take result once it has been
+	// recorded, starting with those that have been recorded already. It is called
+	// from the group's own goroutines, possibly concurrently.
+	AddTakeListener(listener func(SupplyTakeResult))
+
+	// Draining drains and then terminates every pile in the group. The returned
+	// channel is closed once all their take results have been recorded.
+	Drainer
+
+	// Get the number of Suppliables left in each pile once draining has
+	// completed, or nil before.
+	Remaining() map[string]int
 	SupplyPileContribMap() map[string]int
 	SupplyTakerContribMap() map[string]int
 	Taken() []SupplyTakeResult
 }
 
 type supplyPileGroup struct {
-	mutex       sync.RWMutex
+	*lifecycle
+	selection  SelectionStrategy
+	mutex      sync.RWMutex
+	drainOnce  sync.Once
+	drainedCh  chan interface{}
+	forwarders sync.WaitGroup
+	listeners  []func(SupplyTakeResult)
+	remaining  map[string]int
+	taken      []SupplyTakeResult
+
+	// This mutex guards the membership, which is separate from the results.
+	memberMutex sync.Mutex
+	draining    bool
+	routers     []*pileRouter
 	supplyPiles []FSupplyPile
-	taken       []SupplyTakeResult
+	takers      []SupplyTaker
 }
 
+// Get the piles that are currently in the group.
+func (spg *supplyPileGroup) members() []FSupplyPile {
+	spg.memberMutex.Lock()
+	defer spg.memberMutex.Unlock()
+	return append([]FSupplyPile{}, spg.supplyPiles...)
+}
+
+// Find a pile by its ID. Only call this while holding the member mutex.
+func (spg *supplyPileGroup) find(id string) (int, FSupplyPile) {
+	for ix, pile := range spg.supplyPiles {
+		if pile.SupplyPileID() == id {
+			return ix, pile
+		}
+	}
+
+	return -1, nil
+}
+
+func (spg *supplyPileGroup) AddPile(pile FSupplyPile) error {
+	spg.memberMutex.Lock()
+	defer spg.memberMutex.Unlock()
+	id := pile.SupplyPileID()
+
+	if spg.draining {
+		return ErrGroupStopped
+	}
+
+	if _, existing := spg.find(id); existing != nil {
+		return fmt.Errorf("%w: %s", ErrDuplicatePile, id)
+	}
+
+	if !spg.record(pile) {
+		return ErrGroupStopped
+	}
+
+	spg.supplyPiles = append(spg.supplyPiles, pile)
+
+	for _, taker := range spg.takers {
+		go pile.Supply(taker)
+	}
+
+	for _, router := range spg.routers {
+		go pile.Supply(router.add(pile))
+	}
+
+	return nil
+}
+
+func (spg *supplyPileGroup) RemovePile(id string, destinationID string) ([]Suppliable, error) {
+	spg.memberMutex.Lock()
+	ix, pile := spg.find(id)
+
+	if pile == nil {
+		spg.memberMutex.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPile, id)
+	}
+
+	// A pile cannot be its own destination, since it is no longer in the group
+	// once removed.
+	if _, destination := spg.find(destinationID); destinationID != "" &&
+		(destination == nil || destinationID == id) {
+		spg.memberMutex.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPile, destinationID)
+	}
+
+	spg.supplyPiles = append(spg.supplyPiles[:ix], spg.supplyPiles[ix+1:]...)
+
+	for _, router := range spg.routers {
+		router.remove(id)
+	}
+
+	spg.memberMutex.Unlock()
+
+	// Terminating the drained pile closes its take result channel, which ends
+	// its recording loop once every result has been recorded.
+	<-pile.Drain()
+	leftovers := pile.Withdraw()
+	pile.Terminate()
+
+	if destinationID == "" {
+		return leftovers, nil
+	}
+
+	// The destination is looked up again, in case it has been removed in the
+	// meantime. Depositing while holding the mutex means that it cannot be
+	// removed before its own leftovers are withdrawn.
+	spg.memberMutex.Lock()
+	defer spg.memberMutex.Unlock()
+
+	if _, destination := spg.find(destinationID); destination != nil {
+		for ix, supply := range leftovers {
+			if err := destination.Deposit(supply); err != nil {
+				return leftovers[ix:], err
+			}
+		}
+
+		return nil, nil
+	}
+
+	return leftovers, fmt.Errorf("%w: %s", ErrUnknownPile, destinationID)
+}
+
+func (spg *supplyPileGroup) AddTakeListener(listener func(SupplyTakeResult)) {
+	spg.mutex.Lock()
+	taken := spg.taken
+	spg.listeners = append(spg.listeners, listener)
+	spg.mutex.Unlock()
+
+	for _, result := range taken {
+		listener(result)
+	}
+}
+
+// Piles added later also supply the taker.
 func (spg *supplyPileGroup) Supply(taker SupplyTaker) {
+	spg.memberMutex.Lock()
+	defer spg.memberMutex.Unlock()
+
+	if spg.selection != nil {
+		router := newPileRouter(taker, spg.selection)
+
+		if !spg.spawn(func() { router.loop(spg.ctx) }) {
+			return
+		}
+
+		spg.routers = append(spg.routers, router)
+
+		for _, pile := range spg.supplyPiles {
+			go pile.Supply(router.add(pile))
+		}
+
+		return
+	}
+
+	spg.takers = append(spg.takers, taker)
+
 	for _, pile := range spg.supplyPiles {
 		go pile.Supply(taker)
 	}
 }
 
+func (spg *supplyPileGroup) Drain() <-chan interface{} {
+	spg.drainOnce.Do(func() {
+		spg.memberMutex.Lock()
+		spg.draining = true
+		spg.memberMutex.Unlock()
+		piles := spg.members()
+
+		go func() {
+			remaining := make(map[string]int, 0)
+
+			for _, pile := range piles {
+				<-pile.Drain()
+				remaining[pile.SupplyPileID()] = pile.Remaining()
+			}
+
+			// Terminating drained piles closes their take result channels, which in
+			// turn ends the recording loops once every result has been recorded.
+			for _, pile := range piles {
+				pile.Terminate()
+			}
+
+			spg.forwarders.Wait()
+			spg.mutex.Lock()
+			spg.remaining = remaining
+			spg.mutex.Unlock()
+			close(spg.drainedCh)
+		}()
+	})
+
+	return spg.drainedCh
+}
+
 func (spg *supplyPileGroup) SupplyPileContribMap() map[string]int {
 	spg.mutex.RLock()
 	taken := spg.taken
@@ -54,40 +281,88 @@ func (spg *supplyPileGroup) SupplyTakerContribMap() map[string]int {
 	return contributorMap
 }
 
+func (spg *supplyPileGroup) Remaining() map[string]int {
+	spg.mutex.RLock()
+	defer spg.mutex.RUnlock()
+	return spg.remaining
+}
+
 func (spg *supplyPileGroup) Taken() []SupplyTakeResult {
 	spg.mutex.RLock()
 	defer spg.mutex.RUnlock()
 	return spg.taken
 }
 
-// Loop supply to store available piles and take results.
-func (spg *supplyPileGroup) loopSupply() {
-	for _, pile := range spg.supplyPiles {
-		go func(pile FSupplyPile) {
-			for {
-				result, ok := <-pile.TakeResultChannel()
-
-				if ok {
-					// Note that this mutex is only used to modify the result map, since
-					// said map will be accessible via a getter method.
-					spg.mutex.Lock()
-					spg.taken = append(spg.taken, result)
-					spg.mutex.Unlock()
-				} else {
-					return
+// Loop a pile to record its take results. The loop ends once the pile has
+// terminated and closed its take result channel, so that no result is lost
+// while the group is shutting down. Only call this while holding the member
+// mutex, so that no loop is added once the group has started draining.
+func (spg *supplyPileGroup) record(pile FSupplyPile) bool {
+	spg.forwarders.Add(1)
+
+	spawned := spg.spawn(func() {
+		defer spg.forwarders.Done()
+
+		for {
+			result, ok := <-pile.TakeResultChannel()
+
+			if ok {
+				// Note that this mutex is only used to modify the result map, since
+				// said map will be accessible via a getter method.
+				spg.mutex.Lock()
+				spg.taken = append(spg.taken, result)
+				listeners := spg.listeners
+				spg.mutex.Unlock()
+
+				for _, listener := range listeners {
+					listener(result)
 				}
+			} else {
+				return
 			}
-		}(pile)
+		}
+	})
+
+	if !spawned {
+		spg.forwarders.Done()
 	}
+
+	return spawned
+}
+
+// NewSupplyPileGroup creates a new SupplyPileGroup from a number of SupplyPiles,
+// which race for every ready signal. Cancelling the context stops the group as
+// well as all of its piles.
+func NewSupplyPileGroup(ctx context.Context, piles ...FSupplyPile) SupplyPileGroup {
+	return NewSelectiveSupplyPileGroup(ctx, nil, piles...)
 }
 
-// NewSupplyPileGroup creates a new SupplyPileGroup from a number of SupplyPiles.
-func NewSupplyPileGroup(piles ...FSupplyPile) SupplyPileGroup {
-	group := &supplyPileGroup{
-		supplyPiles: piles,
+// NewSelectiveSupplyPileGroup creates a new SupplyPileGroup whose piles are
+// picked by a selection strategy, which may be nil to let them race instead.
+func NewSelectiveSupplyPileGroup(
+	ctx context.Context,
+	selection SelectionStrategy,
+	piles ...FSupplyPile,
+) SupplyPileGroup {
+	var group *supplyPileGroup
+
+	terminateAll := func() {
+		for _, pile := range group.members() {
+			pile.Terminate()
+		}
+	}
+
+	group = &supplyPileGroup{
+		lifecycle:   newLifecycle(ctx, terminateAll, nil),
+		drainedCh:   make(chan interface{}),
+		selection:   selection,
+		supplyPiles: append([]FSupplyPile{}, piles...),
 		taken:       make([]SupplyTakeResult, 0),
 	}
 
-	go group.loopSupply()
+	for _, pile := range group.supplyPiles {
+		group.record(pile)
+	}
+
 	return group
 }
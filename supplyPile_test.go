@@ -1,7 +1,9 @@
 package goburnbooks
 
 import (
-	"os"
+	"context"
+	"errors"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -12,8 +14,13 @@ func Test_SupplyTakersHavingOddCapacity_ShouldStillLoadAll(t *testing.T) {
 	suite := NewDefaultTestSuite()
 	supplyPiles, _, _ := suite.SupplyPiles()
 	totalSupplyCount := int(suite.TotalSupplyCount())
-	pileGroup := NewSupplyPileGroup(supplyPiles...)
+	pileGroup := NewSupplyPileGroup(suite.ctx, supplyPiles...)
 	supplyTakers := suite.SupplyTakers()
+	defer pileGroup.Terminate()
+
+	for _, taker := range supplyTakers {
+		defer taker.Terminate()
+	}
 
 	/// When
 	for _, taker := range supplyTakers {
@@ -45,6 +52,457 @@ func Test_SupplyTakersHavingOddCapacity_ShouldStillLoadAll(t *testing.T) {
 			t.Errorf("%s should have taken some, but took nothing", key)
 		}
 	}
+}
+
+func Test_AddingPileMidRun_ShouldSupplyKnownTakers(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	suite.supplyPerPileCount = 100
+	suite.supplyPileCount = 2
+	supplyPiles, _, _ := suite.SupplyPiles()
+	totalSupplyCount := int(suite.TotalSupplyCount())
+	pileGroup := NewSupplyPileGroup(suite.ctx, supplyPiles[0])
+	supplyTakers := suite.SupplyTakers()
+	defer pileGroup.Terminate()
+
+	for _, taker := range supplyTakers {
+		defer taker.Terminate()
+		pileGroup.Supply(taker)
+	}
+
+	/// When
+	err := pileGroup.AddPile(supplyPiles[1])
+	deadline := time.Now().Add(suite.waitDuration)
+
+	for totalContribCount(pileGroup.SupplyPileContribMap()) < totalSupplyCount && time.Now().Before(deadline) {
+		time.Sleep(suite.burnDuration)
+	}
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if contrib := pileGroup.SupplyPileContribMap(); contrib["0"] != 100 || contrib["1"] != 100 {
+		t.Errorf("Should have taken everything from both piles, got %v", contrib)
+	}
+
+	if err := pileGroup.AddPile(supplyPiles[1]); !errors.Is(err, ErrDuplicatePile) {
+		t.Errorf("Should not have added twice, got %v", err)
+	}
+}
+
+func Test_RemovingPile_ShouldMoveOrReturnLeftovers(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	suite.supplyPerPileCount = 10
+	suite.supplyPileCount = 2
+	supplyPiles, _, _ := suite.SupplyPiles()
+
+	roomy := NewSupplyPile(suite.ctx, &SupplyPileParams{
+		Capacity:    20,
+		ID:          "roomy",
+		Logger:      suite.logger,
+		Restockable: true,
+	})
+
+	small := NewSupplyPile(suite.ctx, &SupplyPileParams{
+		Capacity:    4,
+		ID:          "small",
+		Logger:      suite.logger,
+		Restockable: true,
+	})
+
+	pileGroup := NewSupplyPileGroup(suite.ctx, append(supplyPiles, roomy, small)...)
+	defer pileGroup.Terminate()
+
+	/// When
+	moved, movedErr := pileGroup.RemovePile("0", "roomy")
+	overflow, overflowErr := pileGroup.RemovePile("1", "small")
+	returned, returnedErr := pileGroup.RemovePile("small", "")
+	_, unknownErr := pileGroup.RemovePile("0", "")
+
+	/// Then
+	if len(moved) != 0 || movedErr != nil || roomy.Remaining() != 10 {
+		t.Errorf("Should have moved everything, got %d left and %v", len(moved), movedErr)
+	}
+
+	if len(overflow) != 6 || overflowErr != ErrPileFull {
+		t.Errorf("Should have returned what did not fit, got %d and %v", len(overflow), overflowErr)
+	}
+
+	if len(returned) != 4 || returnedErr != nil {
+		t.Errorf("Should have returned leftovers, got %d and %v", len(returned), returnedErr)
+	}
+
+	if !errors.Is(unknownErr, ErrUnknownPile) {
+		t.Errorf("Should not have removed twice, got %v", unknownErr)
+	}
+
+	if contrib := pileGroup.SupplyPileContribMap(); len(contrib) != 0 {
+		t.Errorf("Should not have supplied anything, got %v", contrib)
+	}
+}
+
+func Test_RemovingPileIntoExhaustedPile_ShouldReturnLeftovers(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	suite.supplyPerPileCount = 10
+	suite.supplyPileCount = 1
+	supplyPiles, _, _ := suite.SupplyPiles()
+
+	// A pile that is not restockable is closed from the start, so it is
+	// exhausted as soon as it has nothing left.
+	spent := NewSupplyPile(suite.ctx, &SupplyPileParams{
+		Capacity: 20,
+		ID:       "spent",
+		Logger:   suite.logger,
+	})
+
+	pileGroup := NewSupplyPileGroup(suite.ctx, append(supplyPiles, spent)...)
+	defer pileGroup.Terminate()
+
+	/// When
+	leftovers, err := pileGroup.RemovePile("0", "spent")
+
+	/// Then
+	if len(leftovers) != 10 || !errors.Is(err, ErrPileClosed) {
+		t.Errorf("Should have returned every leftover, got %d and %v", len(leftovers), err)
+	}
+
+	if spent.Remaining() != 0 {
+		t.Errorf("Should not have moved anything into the spent pile, got %d", spent.Remaining())
+	}
+}
+
+func Test_RestockingFullPile_ShouldBlockUntilThereIsSpace(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+
+	pile := NewSupplyPile(suite.ctx, &SupplyPileParams{
+		Capacity:    2,
+		ID:          "0",
+		Logger:      suite.logger,
+		Restockable: true,
+	})
+
+	static := NewSupplyPile(suite.ctx, &SupplyPileParams{ID: "1", Logger: suite.logger})
+	defer pile.Terminate()
+	defer static.Terminate()
+	restockedCh := make(chan error, 1)
+
+	books := []Suppliable{
+		NewBook(&BookParams{ID: "0"}),
+		NewBook(&BookParams{ID: "1"}),
+		NewBook(&BookParams{ID: "2"}),
+		NewBook(&BookParams{ID: "3"}),
+		NewBook(&BookParams{ID: "4"}),
+	}
+
+	/// When
+	go func() { restockedCh <- pile.Restock(books...) }()
+	time.Sleep(suite.burnDuration * 10)
+	blocked := pile.Remaining()
+	withdrawn := pile.Withdraw()
+	time.Sleep(suite.burnDuration * 10)
+	pile.Close()
+
+	/// Then
+	if blocked != 2 || len(withdrawn) != 2 {
+		t.Errorf("Should have blocked while full, got %d and withdrew %d", blocked, len(withdrawn))
+	}
+
+	select {
+	case <-restockedCh:
+		t.Errorf("Should have still been blocked before closing")
+	default:
+	}
+
+	select {
+	case err := <-restockedCh:
+		if err != ErrPileClosed {
+			t.Errorf("Should have stopped restocking once closed, got %v", err)
+		}
+
+	case <-time.After(suite.waitDuration):
+		t.Fatalf("Should have stopped restocking once closed")
+	}
+
+	if pile.Stocked() != 4 || pile.Remaining() != 2 || pile.Exhausted() {
+		t.Errorf("Should have stocked 4 with 2 left, got %d and %d", pile.Stocked(), pile.Remaining())
+	}
+
+	if err := static.Restock(books[0]); err != ErrPileClosed || !static.Exhausted() {
+		t.Errorf("Should not have restocked a static pile, got %v", err)
+	}
+}
+
+func Test_RestockingPileWithoutCapacity_ShouldHoldOne(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+
+	pile := NewSupplyPile(suite.ctx, &SupplyPileParams{
+		ID:          "0",
+		Logger:      suite.logger,
+		Restockable: true,
+	})
+
+	defer pile.Terminate()
+	restockedCh := make(chan error, 1)
+
+	/// When
+	go func() { restockedCh <- pile.Restock(NewBook(&BookParams{ID: "0"})) }()
+
+	/// Then
+	select {
+	case err := <-restockedCh:
+		if err != nil {
+			t.Errorf("Should have restocked, got %v", err)
+		}
 
-	os.Exit(0)
+	case <-time.After(suite.waitDuration):
+		t.Fatalf("Should have had room for one")
+	}
+
+	if pile.Remaining() != 1 {
+		t.Errorf("Should have held 1, got %d", pile.Remaining())
+	}
+}
+
+func Test_SimulatingRestockedPile_ShouldRunUntilClosed(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	clock := NewFakeClock(time.Unix(0, 0))
+	suite := NewDefaultTestSuite()
+	suite.clock = clock
+	bookCount := 500
+
+	pile := NewSupplyPile(suite.ctx, &SupplyPileParams{
+		Capacity:    20,
+		Clock:       clock,
+		ID:          "0",
+		Logger:      suite.logger,
+		Restockable: true,
+		TakeTimeout: suite.supplyPileTimeout,
+	})
+
+	simulation := NewSimulation(suite.ctx, &SimulationParams{
+		Clock:        clock,
+		Gophers:      suite.Gophers(),
+		Incinerators: suite.Incinerators(),
+		Logger:       suite.logger,
+		SupplyPiles:  []FSupplyPile{pile},
+	})
+
+	defer simulation.Terminate()
+	players := &TestPlayers{simulation: simulation}
+
+	/// When
+	go func() {
+		for ix := 0; ix < bookCount; ix++ {
+			book := NewBook(&BookParams{
+				BurnDuration: suite.burnDuration,
+				Clock:        clock,
+				ID:           strconv.Itoa(ix),
+			})
+
+			if err := pile.Restock(book); err != nil {
+				return
+			}
+		}
+
+		pile.Close()
+	}()
+
+	report, err := players.WaitAdvancing(clock, suite.waitDuration)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Completed || report.BurnedCount != bookCount || report.SupplyCount != bookCount {
+		t.Errorf("Should have burned %d, got %d of %d", bookCount, report.BurnedCount, report.SupplyCount)
+	}
+}
+
+func Test_StreamingFromIterator_ShouldSupplyEverything(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	clock := NewFakeClock(time.Unix(0, 0))
+	suite := NewDefaultTestSuite()
+	suite.clock = clock
+	bookCount := 1000
+	produced := 0
+
+	pile := NewStreamingSupplyPile(suite.ctx, &SupplyPileParams{
+		Capacity:    10,
+		Clock:       clock,
+		ID:          "0",
+		Logger:      suite.logger,
+		TakeTimeout: suite.supplyPileTimeout,
+	}, ProduceFromIterator(func() (Suppliable, bool) {
+		if produced == bookCount {
+			return nil, false
+		}
+
+		produced++
+
+		return NewBook(&BookParams{
+			BurnDuration: suite.burnDuration,
+			Clock:        clock,
+			ID:           strconv.Itoa(produced),
+		}), true
+	}))
+
+	simulation := NewSimulation(suite.ctx, &SimulationParams{
+		Clock:        clock,
+		Gophers:      suite.Gophers(),
+		Incinerators: suite.Incinerators(),
+		Logger:       suite.logger,
+		SupplyPiles:  []FSupplyPile{pile},
+	})
+
+	defer simulation.Terminate()
+	players := &TestPlayers{simulation: simulation}
+
+	/// When
+	report, err := players.WaitAdvancing(clock, suite.waitDuration)
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Completed || report.BurnedCount != bookCount || report.SupplyCount != bookCount {
+		t.Errorf("Should have burned %d, got %d of %d", bookCount, report.BurnedCount, report.SupplyCount)
+	}
+}
+
+func Test_ProducingFromIteratorOnceDone_ShouldStopCallingIterator(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+
+	producer := ProduceFromIterator(func() (Suppliable, bool) {
+		calls++
+		return NewBook(&BookParams{ID: strconv.Itoa(calls)}), true
+	})
+
+	/// When
+	supply, ok := producer(ctx)
+	cancel()
+	_, okAfterDone := producer(ctx)
+
+	/// Then
+	if !ok || supply.SuppliableID() != "1" {
+		t.Errorf("Should have produced the first book, got %v", supply)
+	}
+
+	if okAfterDone || calls != 1 {
+		t.Errorf("Should have stopped once done, but called the iterator %d times", calls)
+	}
+}
+
+func Test_StreamingFromChannel_ShouldCloseOnceChannelCloses(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	supplyCh := make(chan Suppliable)
+
+	pile := NewStreamingSupplyPile(suite.ctx, &SupplyPileParams{
+		ID:     "0",
+		Logger: suite.logger,
+	}, ProduceFromChannel(supplyCh))
+
+	defer pile.Terminate()
+
+	/// When
+	supplyCh <- NewBook(&BookParams{ID: "0"})
+	supplyCh <- NewBook(&BookParams{ID: "1"})
+	blocked := pile.Remaining()
+	withdrawn := pile.Withdraw()
+	supplyCh <- NewBook(&BookParams{ID: "2"})
+	close(supplyCh)
+	timeoutCh := time.After(suite.waitDuration)
+
+	for !pile.Exhausted() {
+		if len(withdrawn) < 3 {
+			withdrawn = append(withdrawn, pile.Withdraw()...)
+		}
+
+		select {
+		case <-timeoutCh:
+			t.Fatalf("Should have closed the pile, withdrew %d", len(withdrawn))
+
+		case <-time.After(suite.burnDuration):
+		}
+	}
+
+	/// Then
+	if blocked != 1 {
+		t.Errorf("Should have only held 1 book, got %d", blocked)
+	}
+
+	if len(withdrawn) != 3 || pile.Stocked() != 3 {
+		t.Errorf("Should have stocked and withdrawn 3, got %d and %d", pile.Stocked(), len(withdrawn))
+	}
+}
+
+func Test_TerminatingStreamingPile_ShouldStopWaitingProducer(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	supplyCh := make(chan Suppliable)
+
+	pile := NewStreamingSupplyPile(suite.ctx, &SupplyPileParams{
+		ID:     "0",
+		Logger: suite.logger,
+	}, ProduceFromChannel(supplyCh))
+
+	/// When
+	pile.Terminate()
+
+	/// Then
+	select {
+	case supplyCh <- NewBook(&BookParams{ID: "0"}):
+		t.Errorf("Should have stopped receiving from the channel")
+
+	default:
+	}
+}
+
+func Test_ClosingStreamingPile_ShouldReturnDroppedSupply(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	supplyCh := make(chan Suppliable)
+
+	pile := NewStreamingSupplyPile(suite.ctx, &SupplyPileParams{
+		ID:     "0",
+		Logger: suite.logger,
+	}, ProduceFromChannel(supplyCh))
+
+	/// When
+	supplyCh <- NewBook(&BookParams{ID: "0"})
+	pile.Close()
+	supplyCh <- NewBook(&BookParams{ID: "1"})
+	pile.Terminate()
+
+	/// Then
+	dropped := pile.Dropped()
+
+	if len(dropped) != 1 || dropped[0].SuppliableID() != "1" {
+		t.Errorf("Should have dropped only 1, got %v", dropped)
+	}
+
+	if pile.Stocked() != 1 {
+		t.Errorf("Should have stocked only 0, got %d", pile.Stocked())
+	}
 }
@@ -2,19 +2,54 @@ package goburnbooks
 
 import (
 	"fmt"
+	"time"
 )
 
 // SupplyTakeResult represents the result of a take operation.
+//
+// Each take is a batch of its own, numbered from 1 by the pile in the order the
+// loads were ready, with batch IDs such as "pile-1". A load that a draining pile
+// puts back leaves a gap in the numbering. The start time is when the pile began
+// loading for the taker, and the end time is when the taker accepted the load,
+// i.e. when the Suppliables left the pile. Queue wait is how long the loaded
+// Suppliables waited for the taker to accept them. The metadata of the
+// Suppliables is listed in the same order as their ID's.
 type SupplyTakeResult interface {
 	PileID() string
 	TakerID() string
 	SupplyIDs() []string
+	BatchID() string
+	Sequence() uint64
+	StartTime() time.Time
+	EndTime() time.Time
+	QueueWait() time.Duration
+	Metadata() []Metadata
+}
+
+// SupplyTakeResultParams represents the required parameters to build a
+// SupplyTakeResult.
+type SupplyTakeResultParams struct {
+	BatchID   string
+	EndTime   time.Time
+	Metadata  []Metadata
+	PileID    string
+	QueueWait time.Duration
+	Sequence  uint64
+	StartTime time.Time
+	SupplyIDs []string
+	TakerID   string
 }
 
 type supplyTakeResult struct {
+	batchID   string
+	endTime   time.Time
+	metadata  []Metadata
 	pileID    string
-	takerID   string
+	queueWait time.Duration
+	sequence  uint64
+	startTime time.Time
 	supplyIDs []string
+	takerID   string
 }
 
 func (str *supplyTakeResult) PileID() string {
@@ -29,6 +64,30 @@ func (str *supplyTakeResult) SupplyIDs() []string {
 	return str.supplyIDs
 }
 
+func (str *supplyTakeResult) BatchID() string {
+	return str.batchID
+}
+
+func (str *supplyTakeResult) Sequence() uint64 {
+	return str.sequence
+}
+
+func (str *supplyTakeResult) StartTime() time.Time {
+	return str.startTime
+}
+
+func (str *supplyTakeResult) EndTime() time.Time {
+	return str.endTime
+}
+
+func (str *supplyTakeResult) QueueWait() time.Duration {
+	return str.queueWait
+}
+
+func (str *supplyTakeResult) Metadata() []Metadata {
+	return str.metadata
+}
+
 func (str *supplyTakeResult) String() string {
 	return fmt.Sprintf(
 		"Supply taker %s took %d supplies from pile %s",
@@ -38,11 +97,27 @@ func (str *supplyTakeResult) String() string {
 	)
 }
 
-// NewTakeResult returns a new SupplyTakeResult.
+// NewTakeResult returns a new SupplyTakeResult that only records who took
+// what.
 func NewTakeResult(pileID string, takerID string, supplyIDs []string) SupplyTakeResult {
+	return NewSupplyTakeResult(&SupplyTakeResultParams{
+		PileID:    pileID,
+		SupplyIDs: supplyIDs,
+		TakerID:   takerID,
+	})
+}
+
+// NewSupplyTakeResult returns a new SupplyTakeResult.
+func NewSupplyTakeResult(params *SupplyTakeResultParams) SupplyTakeResult {
 	return &supplyTakeResult{
-		pileID:    pileID,
-		takerID:   takerID,
-		supplyIDs: supplyIDs,
+		batchID:   params.BatchID,
+		endTime:   params.EndTime,
+		metadata:  params.Metadata,
+		pileID:    params.PileID,
+		queueWait: params.QueueWait,
+		sequence:  params.Sequence,
+		startTime: params.StartTime,
+		supplyIDs: params.SupplyIDs,
+		takerID:   params.TakerID,
 	}
 }
@@ -1,40 +1,61 @@
 package goburnbooks
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
 
+// SupplyLoad represents Suppliables that a pile hands over to a taker, along
+// with where they come from.
+type SupplyLoad struct {
+	Origin   Location
+	PileID   string
+	Supplies []Suppliable
+}
+
 // SupplyTaker represents a worker that takes Suppliables for some purposes.
 type SupplyTaker interface {
+	Terminator
 	Capacity() uint
 	SupplyTakerID() string
 
-	// This channel receives supplies from supply piles.
-	ReceiveLoadChannel() chan<- []Suppliable
+	// Draining stops the taker from signalling ready to supply piles, while a
+	// load that it has already received is still handed over.
+	Drainer
+
+	// This channel receives loads from supply piles.
+	ReceiveLoadChannel() chan<- SupplyLoad
 
 	// This channel sends ready signal to supply piles.
 	SendTakeReadyChannel() <-chan interface{}
 }
 
 // SupplyTakerRawParams represents only the immutable parameters used to build
-// a taker.
+// a taker. The cap is the total weight it takes at once, or no limit if it is
+// 0, where Suppliables that are not Weighted weigh 1.
 type SupplyTakerRawParams struct {
 	Cap         uint
+	Clock       Clock
 	STID        string
 	TakeTimeout time.Duration
 }
 
 // SupplyTakerParams represents all the required parameters to build a taker.
+// Loads are passed on whole if the load destination is set, and only their
+// supplies are passed on otherwise.
 type SupplyTakerParams struct {
 	SupplyTakerRawParams
+	SendLoadDestCh   chan<- SupplyLoad
 	SendSupplyDestCh chan<- []Suppliable
 	STLogger         Logger
 }
 
 type supplyTaker struct {
+	*lifecycle
 	SupplyTakerParams
-	receiveLoadCh   chan []Suppliable
+	log             LeveledLogger
+	receiveLoadCh   chan SupplyLoad
 	sendTakeReadyCh chan interface{}
 }
 
@@ -46,7 +67,7 @@ func (st *supplyTaker) Capacity() uint {
 	return st.Cap
 }
 
-func (st *supplyTaker) ReceiveLoadChannel() chan<- []Suppliable {
+func (st *supplyTaker) ReceiveLoadChannel() chan<- SupplyLoad {
 	return st.receiveLoadCh
 }
 
@@ -54,60 +75,100 @@ func (st *supplyTaker) SendTakeReadyChannel() <-chan interface{} {
 	return st.sendTakeReadyCh
 }
 
+func (st *supplyTaker) Drain() <-chan interface{} {
+	return st.drain()
+}
+
 func (st *supplyTaker) SupplyTakerID() string {
 	return st.STID
 }
 
 func (st *supplyTaker) loopWork() {
-	logger := st.STLogger
+	logger := st.log
 	sendTakeReadyCh := st.sendTakeReadyCh
 	resetSequenceCh := make(chan interface{}, 1)
-	var receiveLoadCh chan []Suppliable
+	drainingCh := st.drainingCh
+	var draining bool
+	var load SupplyLoad
+	var receiveLoadCh chan SupplyLoad
+	var sendLoadDestCh chan<- SupplyLoad
 	var sendSupplyDestCh chan<- []Suppliable
-	var suppliables []Suppliable
 	var takeTimeoutCh <-chan time.Time
 
 	for {
 		select {
+		case <-st.ctx.Done():
+			return
+
 		case sendTakeReadyCh <- true:
 			sendTakeReadyCh = nil
 			receiveLoadCh = st.receiveLoadCh
-			takeTimeoutCh = time.After(st.TakeTimeout)
+			takeTimeoutCh = st.Clock.After(st.TakeTimeout)
 
 		// >>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>
-		case suppliables = <-receiveLoadCh:
-			logger.Printf("%v received %d supplies from source", st, len(suppliables))
+		case load = <-receiveLoadCh:
+			logger.Debug(
+				"received supplies",
+				Field(FieldBookCount, len(load.Supplies)),
+				Field(FieldPeerID, load.PileID),
+			)
+
 			receiveLoadCh = nil
 			takeTimeoutCh = nil
-			sendSupplyDestCh = st.SendSupplyDestCh
+
+			if st.SendLoadDestCh != nil {
+				sendLoadDestCh = st.SendLoadDestCh
+			} else {
+				sendSupplyDestCh = st.SendSupplyDestCh
+			}
 
 		case <-takeTimeoutCh:
-			logger.Printf("%v timed out!", st)
+			logger.Debug("timed out")
 			takeTimeoutCh = nil
 			receiveLoadCh = nil
 			resetSequenceCh <- true
 		// >>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>
 
-		case sendSupplyDestCh <- suppliables:
-			logger.Printf("%v sent %d supplies to destination", st, len(suppliables))
+		case sendLoadDestCh <- load:
+			logger.Debug("sent load", Field(FieldBookCount, len(load.Supplies)))
+			sendLoadDestCh = nil
+			load = SupplyLoad{}
+			resetSequenceCh <- true
+
+		case sendSupplyDestCh <- load.Supplies:
+			logger.Debug("sent supplies", Field(FieldBookCount, len(load.Supplies)))
 			sendSupplyDestCh = nil
-			suppliables = nil
+			load = SupplyLoad{}
 			resetSequenceCh <- true
 
+		case <-drainingCh:
+			drainingCh = nil
+			draining = true
+
 		case <-resetSequenceCh:
 			sendTakeReadyCh = st.sendTakeReadyCh
 		}
+
+		// A taker that would signal ready again holds no load, nor waits for one.
+		if draining && sendTakeReadyCh != nil {
+			logger.Info("drained")
+			return
+		}
 	}
 }
 
-// NewSupplyTaker creates a new SupplyTaker.
-func NewSupplyTaker(params *SupplyTakerParams) SupplyTaker {
+// NewSupplyTaker creates a new SupplyTaker. Cancelling the context stops the
+// taker. It times out on the real clock if none is specified.
+func NewSupplyTaker(ctx context.Context, params *SupplyTakerParams) SupplyTaker {
 	supplyTaker := &supplyTaker{
+		lifecycle:         newLifecycle(ctx, nil, nil),
 		SupplyTakerParams: *params,
-		receiveLoadCh:     make(chan []Suppliable),
+		receiveLoadCh:     make(chan SupplyLoad),
 		sendTakeReadyCh:   make(chan interface{}),
 	}
 
-	go supplyTaker.loopWork()
+	supplyTaker.Clock = clockOrDefault(supplyTaker.Clock)
+	supplyTaker.log = Leveled(supplyTaker.STLogger).With(Field(FieldActor, "taker"), Field(FieldActorID, supplyTaker.STID))
+	supplyTaker.spawn(supplyTaker.loopWork)
 	return supplyTaker
 }
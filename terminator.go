@@ -1,6 +1,131 @@
 package goburnbooks
 
-// Terminator represents something that can terminate.
+import (
+	"context"
+	"sync"
+)
+
+// Terminator represents something that can terminate. Terminate() blocks until
+// every goroutine belonging to the terminator has returned.
 type Terminator interface {
 	Terminate()
 }
+
+// Drainer represents something that can stop taking on new work while letting
+// the work it has already taken on complete. The returned channel is closed
+// once everything has wound down.
+type Drainer interface {
+	Drain() <-chan interface{}
+}
+
+// lifecycle coordinates the goroutines of an actor. Once its context is done,
+// release is called to let go of anything said goroutines may be waiting on
+// (e.g. child actors), then all goroutines are awaited and finally cleanUp is
+// called, which is usually where result channels are closed.
+type lifecycle struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	doneCh      chan interface{}
+	drainOnce   sync.Once
+	drainedCh   chan interface{}
+	drainingCh  chan interface{}
+	sealed      bool
+	spawnMutex  sync.Mutex
+	spawnWaiter sync.WaitGroup
+}
+
+// Start a tracked goroutine, unless the lifecycle has already been sealed.
+func (lc *lifecycle) spawn(fn func()) bool {
+	lc.spawnMutex.Lock()
+	defer lc.spawnMutex.Unlock()
+
+	if lc.sealed || lc.ctx.Err() != nil {
+		return false
+	}
+
+	lc.fork(fn)
+	return true
+}
+
+// Start a tracked goroutine from within another tracked goroutine. Since the
+// parent is still being tracked, this is safe even after sealing.
+func (lc *lifecycle) fork(fn func()) {
+	lc.spawnWaiter.Add(1)
+
+	go func() {
+		defer lc.spawnWaiter.Done()
+		fn()
+	}()
+}
+
+// Prevent new goroutines from being spawned.
+func (lc *lifecycle) seal() {
+	lc.spawnMutex.Lock()
+	defer lc.spawnMutex.Unlock()
+	lc.sealed = true
+}
+
+// Check whether new goroutines can no longer be spawned.
+func (lc *lifecycle) isSealed() bool {
+	lc.spawnMutex.Lock()
+	defer lc.spawnMutex.Unlock()
+	return lc.sealed || lc.ctx.Err() != nil
+}
+
+// Wait for all tracked goroutines to return. Only call this after sealing.
+func (lc *lifecycle) wait() {
+	lc.spawnWaiter.Wait()
+}
+
+// Stop spawning new goroutines and signal the tracked ones to wind down via the
+// draining channel. The returned channel is closed once all of them have
+// returned.
+func (lc *lifecycle) drain() <-chan interface{} {
+	lc.drainOnce.Do(func() {
+		lc.seal()
+		close(lc.drainingCh)
+
+		go func() {
+			lc.wait()
+			close(lc.drainedCh)
+		}()
+	})
+
+	return lc.drainedCh
+}
+
+func (lc *lifecycle) Terminate() {
+	lc.cancel()
+	<-lc.doneCh
+}
+
+func newLifecycle(ctx context.Context, release func(), cleanUp func()) *lifecycle {
+	ctx, cancel := context.WithCancel(ctx)
+
+	lc := &lifecycle{
+		ctx:        ctx,
+		cancel:     cancel,
+		doneCh:     make(chan interface{}),
+		drainedCh:  make(chan interface{}),
+		drainingCh: make(chan interface{}),
+	}
+
+	go func() {
+		<-ctx.Done()
+		lc.seal()
+
+		if release != nil {
+			release()
+		}
+
+		lc.wait()
+
+		if cleanUp != nil {
+			cleanUp()
+		}
+
+		close(lc.doneCh)
+	}()
+
+	return lc
+}
@@ -0,0 +1,65 @@
+package goburnbooks
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_TerminatingSystem_ShouldCloseAllResultChannels(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	suite.supplyPerPileCount = 100
+	players := suite.SetUpSystem()
+	time.Sleep(suite.tripDelay)
+
+	/// When
+	players.Terminate()
+
+	/// Then
+	for _, pile := range players.supplyPiles {
+		if _, ok := <-pile.TakeResultChannel(); ok {
+			t.Errorf("%v should have closed its take result channel", pile)
+		}
+	}
+
+	for _, incinerator := range players.incinerators {
+		if _, ok := <-incinerator.BurnResultChannel(); ok {
+			t.Errorf("%v should have closed its burn result channel", incinerator)
+		}
+	}
+
+	burnResultCh := players.incineratorGroup.BurnResultChannel()
+
+	for {
+		if _, ok := <-burnResultCh; !ok {
+			break
+		}
+	}
+}
+
+func Test_CancellingContext_ShouldStopAllActors(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	ctx, cancel := context.WithCancel(suite.ctx)
+	suite.ctx = ctx
+	players := suite.SetUpSystem()
+	terminatedCh := make(chan interface{})
+
+	/// When
+	cancel()
+
+	go func() {
+		players.Terminate()
+		terminatedCh <- true
+	}()
+
+	/// Then
+	select {
+	case <-terminatedCh:
+	case <-time.After(suite.waitDuration):
+		t.Errorf("Should have terminated after cancellation")
+	}
+}
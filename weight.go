@@ -0,0 +1,35 @@
+package goburnbooks
+
+// Weighted represents something that takes up capacity according to its size.
+// Anything that is not Weighted, or has no weight, weighs 1, so capacities
+// count items as long as nothing is weighted.
+type Weighted interface {
+	Weight() uint
+}
+
+// Get the weight of a Burnable or Suppliable.
+func weightOf(value interface{}) uint {
+	if weighted, ok := value.(Weighted); ok && weighted.Weight() > 0 {
+		return weighted.Weight()
+	}
+
+	return 1
+}
+
+// Sum up the weights of a number of Burnables.
+func totalWeight(burnables ...Burnable) uint {
+	var total uint
+
+	for _, burnable := range burnables {
+		total += weightOf(burnable)
+	}
+
+	return total
+}
+
+// Check whether something of some weight fits into a load that is limited to a
+// capacity, where 0 means no limit. Anything fits into an empty load, so that
+// nothing heavier than the capacity is stuck forever.
+func fitsWeight(loaded uint, weight uint, capacity uint) bool {
+	return capacity == 0 || loaded == 0 || loaded+weight <= capacity
+}
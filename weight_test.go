@@ -0,0 +1,287 @@
+package goburnbooks
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const weightScenario = `{
+	"supplyPiles": [
+		{
+			"id": "0",
+			"takeTimeout": "1ms",
+			"books": [
+				{"id": "a", "burnDuration": "1s", "priority": 4, "weight": 3},
+				{"id": "b", "burnDuration": "1s", "priority": 3, "weight": 3},
+				{"id": "c", "burnDuration": "1s", "priority": 2},
+				{"id": "d", "burnDuration": "1s", "priority": 1, "weight": 2},
+				{"id": "e", "burnDuration": "1s", "weight": 9}
+			]
+		}
+	],
+	"gophers": [{"id": "0", "capacity": 5, "takeTimeout": "1ms", "tripDuration": "1ms"}],
+	"incinerators": [{"id": "0", "capacity": 5, "minCapacity": 2}]
+}`
+
+// A Burnable that keeps track of the peak weight burning at once among all
+// those that share its counters.
+type trackedBurnable struct {
+	Burnable
+	burning *int64
+	peak    *int64
+}
+
+func (tb *trackedBurnable) Weight() uint {
+	return weightOf(tb.Burnable)
+}
+
+func (tb *trackedBurnable) Burn() {
+	weight := int64(tb.Weight())
+	burning := atomic.AddInt64(tb.burning, weight)
+
+	for peak := atomic.LoadInt64(tb.peak); burning > peak; peak = atomic.LoadInt64(tb.peak) {
+		if atomic.CompareAndSwapInt64(tb.peak, peak, burning) {
+			break
+		}
+	}
+
+	tb.Burnable.Burn()
+	atomic.AddInt64(tb.burning, -weight)
+}
+
+func Test_SchedulingWeightedBurns_ShouldStayWithinCapacity(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	scheduler := newBurnScheduler(4, 0, NewFakeClock(time.Unix(0, 0)))
+
+	enqueue := func(weights ...uint) []*burnTicket {
+		burnables := make([]Burnable, len(weights))
+
+		for ix, weight := range weights {
+			burnables[ix] = NewBook(&BookParams{Weight: weight})
+		}
+
+		return scheduler.enqueue(burnables...)
+	}
+
+	granted := func(tickets ...*burnTicket) []bool {
+		results := make([]bool, len(tickets))
+
+		for ix, ticket := range tickets {
+			select {
+			case <-ticket.grantCh:
+				results[ix] = true
+
+			default:
+			}
+		}
+
+		return results
+	}
+
+	/// When
+	tickets := enqueue(3, 2, 1)
+	heavy := enqueue(9)[0]
+	first := granted(append(tickets, heavy)...)
+	scheduler.release(3)
+	second := granted(append(tickets, heavy)...)
+	scheduler.release(2)
+	scheduler.release(1)
+	third := granted(heavy)
+
+	/// Then
+	if expected := []bool{true, false, false, false}; !reflect.DeepEqual(first, expected) {
+		t.Errorf("Should have held up the lighter tickets behind one that does not fit, got %v", first)
+	}
+
+	if expected := []bool{true, true, true, false}; !reflect.DeepEqual(second, expected) {
+		t.Errorf("Should have granted everything that fits once released, got %v", second)
+	}
+
+	if !third[0] {
+		t.Errorf("Should have granted a ticket heavier than the capacity once nothing burns")
+	}
+}
+
+func Test_TakingWeightedBooks_ShouldFillToWeightLimit(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	ctx := context.Background()
+	clock := NewFakeClock(time.Unix(0, 0))
+	scenario, err := LoadScenario(strings.NewReader(weightScenario))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	simulation := NewSimulation(ctx, scenario.SimulationParams(ctx, &ScenarioParams{Clock: clock}))
+	defer simulation.Terminate()
+	players := &TestPlayers{simulation: simulation}
+
+	/// When
+	report, err := players.WaitAdvancing(clock, time.Duration(5e9))
+
+	/// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Completed || report.BurnedCount != 5 {
+		t.Errorf("Should have burned 5, got %d", report.BurnedCount)
+	}
+
+	loads := make([][]string, 0)
+
+	for _, result := range simulation.SupplyPileGroup().Taken() {
+		loads = append(loads, result.SupplyIDs())
+	}
+
+	expected := [][]string{{"a"}, {"b", "c"}, {"d"}, {"e"}}
+
+	if !reflect.DeepEqual(loads, expected) {
+		t.Errorf("Should have taken %v, got %v", expected, loads)
+	}
+
+	for _, difference := range CompareReports(report, RunDiscreteSimulation(scenario.DiscreteParams()), 0.05) {
+		t.Error(difference)
+	}
+}
+
+func Test_BurningFromManyProviders_ShouldShareCapacity(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	ctx, cancel := context.WithCancel(suite.ctx)
+	suite.ctx = ctx
+
+	// Burns in progress cannot be interrupted, so only cancel the context
+	// instead of terminating, which would wait for said burns if this fails.
+	defer cancel()
+
+	clock := NewFakeClock(time.Unix(0, 0))
+	var burning, peak int64
+
+	incinerator := NewIncinerator(suite.ctx, &IncineratorParams{
+		Capacity: 3,
+		Clock:    clock,
+		ID:       "0",
+		Logger:   suite.logger,
+	})
+
+	providers := []*stubProvider{
+		{burnablesCh: make(chan []Burnable), readyCh: make(chan string)},
+		{burnablesCh: make(chan []Burnable), readyCh: make(chan string)},
+	}
+
+	book := func(id string, weight uint) Burnable {
+		return &trackedBurnable{
+			Burnable: NewBook(&BookParams{BurnDuration: time.Second, Clock: clock, ID: id, Weight: weight}),
+			burning:  &burning,
+			peak:     &peak,
+		}
+	}
+
+	/// When
+	for ix, provider := range providers {
+		incinerator.Consume(provider)
+		<-provider.readyCh
+		provider.burnablesCh <- []Burnable{book(fmt.Sprintf("%d-a", ix), 2), book(fmt.Sprintf("%d-b", ix), 1)}
+	}
+
+	timeoutCh := time.After(suite.waitDuration)
+
+	for burned := 0; burned < 4; {
+		select {
+		case <-incinerator.BurnResultChannel():
+			burned++
+
+		case <-time.After(suite.burnDuration * 10):
+			clock.Advance(time.Second)
+
+		case <-timeoutCh:
+			t.Fatalf("Should have burned 4 books, got %d", burned)
+		}
+	}
+
+	/// Then
+	if peak := atomic.LoadInt64(&peak); peak > 3 {
+		t.Errorf("Should have burned at most 3 in weight at once, got %d", peak)
+	}
+}
+
+func Test_BurningManyBooksFromManyProviders_ShouldBurnAllInTime(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	providerCount := 10
+	batchCount := 5
+	batchSize := 1000
+	bookCount := providerCount * batchCount * batchSize
+
+	// Every provider feeds the same scheduler, which once scanned all of their
+	// waiting books for every grant and took minutes for this many.
+	maxWallTime := 30 * time.Second
+
+	incinerator := NewIncinerator(suite.ctx, &IncineratorParams{
+		Capacity:    suite.incineratorCap,
+		ID:          "0",
+		Logger:      suite.logger,
+		MinCapacity: suite.incineratorMinCap,
+	})
+
+	defer incinerator.Terminate()
+
+	for pix := 0; pix < providerCount; pix++ {
+		sourceCh := make(chan []Burnable)
+
+		provider := NewBurnableProvider(suite.ctx, &BurnableProviderParams{
+			BurnableProviderRawParams: BurnableProviderRawParams{BPID: fmt.Sprint(pix)},
+			ReceiveBurnableSourceCh:   sourceCh,
+		})
+
+		defer provider.Terminate()
+		incinerator.Consume(provider)
+
+		go func(pix int) {
+			for bix := 0; bix < batchCount; bix++ {
+				burnables := make([]Burnable, batchSize)
+
+				for ix := range burnables {
+					burnables[ix] = NewBook(&BookParams{ID: fmt.Sprintf("%d-%d-%d", pix, bix, ix)})
+				}
+
+				select {
+				case sourceCh <- burnables:
+				case <-suite.ctx.Done():
+					return
+				}
+			}
+		}(pix)
+	}
+
+	burned := make(map[string]int, bookCount)
+	timeoutCh := time.After(maxWallTime)
+
+	/// When
+	for len(burned) < bookCount {
+		select {
+		case result := <-incinerator.BurnResultChannel():
+			burned[result.Burned().BurnableID()]++
+
+		case <-timeoutCh:
+			t.Fatalf("Should have burned %d within %v, got %d", bookCount, maxWallTime, len(burned))
+		}
+	}
+
+	/// Then
+	for id, count := range burned {
+		if count != 1 {
+			t.Errorf("Should have burned %s once, got %d", id, count)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package goburnbooks
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_StealingBurnTickets_ShouldNeverGrantThemToo(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	scheduler := newBurnScheduler(1, 0, NewFakeClock(time.Unix(0, 0)))
+
+	thief := NewIncinerator(suite.ctx, &IncineratorParams{
+		Accepts:  []Category{"paper"},
+		Capacity: 5,
+		ID:       "thief",
+	}).(stealer)
+
+	defer thief.Terminate()
+
+	tickets := scheduler.enqueue(
+		NewBook(&BookParams{ID: "burning"}),
+		NewBook(&BookParams{ID: "paper", Metadata: Metadata{Material: "paper"}}),
+		NewBook(&BookParams{ID: "hard", Metadata: Metadata{Material: "hardcover"}}),
+	)
+
+	closed := func(ch chan interface{}) bool {
+		select {
+		case <-ch:
+			return true
+
+		default:
+			return false
+		}
+	}
+
+	/// When
+	stolen := scheduler.steal(thief, 5)
+	scheduler.release(1)
+
+	/// Then
+	if stolen != 1 {
+		t.Errorf("Should have stolen only the accepted book, got weight %d", stolen)
+	}
+
+	granted := []bool{closed(tickets[0].grantCh), closed(tickets[1].grantCh), closed(tickets[2].grantCh)}
+
+	if expected := []bool{true, false, true}; !reflect.DeepEqual(granted, expected) {
+		t.Errorf("Should have granted %v, got %v", expected, granted)
+	}
+
+	if !closed(tickets[1].stolenCh) || tickets[1].thief != thief {
+		t.Errorf("Should have handed the stolen book to the thief")
+	}
+
+	if closed(tickets[2].stolenCh) || tickets[2].thief != nil {
+		t.Errorf("Should not have stolen a book that the thief does not accept")
+	}
+}
+
+func Test_StealingWorkInGroup_ShouldBurnQueuedBooksOnIdleIncinerator(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	clock := NewFakeClock(time.Unix(0, 0))
+	sourceCh := make(chan []Burnable)
+	slow := NewIncinerator(suite.ctx, &IncineratorParams{Capacity: 1, Clock: clock, ID: "slow"})
+	idle := NewIncinerator(suite.ctx, &IncineratorParams{Capacity: 4, Clock: clock, ID: "idle"})
+
+	group := NewIncineratorGroup(suite.ctx, &IncineratorGroupParams{
+		Incinerators: []FIncinerator{slow},
+		WorkStealing: true,
+	})
+
+	defer group.Terminate()
+
+	provider := NewBurnableProvider(suite.ctx, &BurnableProviderParams{
+		BurnableProviderRawParams: BurnableProviderRawParams{BPID: "0"},
+		ReceiveBurnableSourceCh:   sourceCh,
+	})
+
+	defer provider.Terminate()
+
+	book := func(id string, priority int, burnDuration time.Duration) Burnable {
+		return NewBook(&BookParams{BurnDuration: burnDuration, Clock: clock, ID: id, Priority: priority})
+	}
+
+	results := make([]BurnResult, 0)
+
+	receive := func(count int) {
+		for len(results) < count {
+			select {
+			case result := <-group.BurnResultChannel():
+				results = append(results, result)
+
+			case <-time.After(suite.waitDuration):
+				t.Fatalf("Should have burned %d books, got %d", count, len(results))
+			}
+		}
+	}
+
+	/// When
+	group.Consume(provider)
+	sourceCh <- []Burnable{book("long", 1, time.Hour), book("a", 0, 0), book("b", 0, 0), book("c", 0, 0)}
+
+	if err := group.Add(idle); err != nil {
+		t.Fatal(err)
+	}
+
+	receive(3)
+	clock.Advance(time.Hour)
+	receive(4)
+
+	/// Then
+	for _, result := range results {
+		id := result.Burned().BurnableID()
+
+		if id == "long" && (result.IncineratorID() != "slow" || result.StolenFrom() != "") {
+			t.Errorf("Should have burned long where it was queued, got %s", result.IncineratorID())
+		}
+
+		if id != "long" && (result.IncineratorID() != "idle" || result.StolenFrom() != "slow") {
+			t.Errorf("Should have stolen %s from slow, got %s", id, result.IncineratorID())
+		}
+	}
+
+	expected := map[string]int{"a": 1, "b": 1, "c": 1, "long": 1}
+
+	if burned := group.BurnedIDMap(); !reflect.DeepEqual(burned, expected) {
+		t.Errorf("Should have burned %v exactly once, got %v", expected, burned)
+	}
+}
+
+func Test_StealingWorkInGroup_ShouldStayWithinThiefCapacity(t *testing.T) {
+	/// Setup
+	t.Parallel()
+	suite := NewDefaultTestSuite()
+	ctx, cancel := context.WithCancel(suite.ctx)
+	suite.ctx = ctx
+
+	// Burns in progress cannot be interrupted, so only cancel the context
+	// instead of terminating, which would wait for said burns if this fails.
+	defer cancel()
+
+	clock := NewFakeClock(time.Unix(0, 0))
+	sourceCh := make(chan []Burnable)
+	slow := NewIncinerator(suite.ctx, &IncineratorParams{Capacity: 1, Clock: clock, ID: "slow"})
+	thief := NewIncinerator(suite.ctx, &IncineratorParams{Capacity: 2, Clock: clock, ID: "thief"})
+	var burning, peak int64
+
+	group := NewIncineratorGroup(suite.ctx, &IncineratorGroupParams{
+		Incinerators: []FIncinerator{slow},
+		WorkStealing: true,
+	})
+
+	provider := NewBurnableProvider(suite.ctx, &BurnableProviderParams{
+		BurnableProviderRawParams: BurnableProviderRawParams{BPID: "0"},
+		ReceiveBurnableSourceCh:   sourceCh,
+	})
+
+	defer provider.Terminate()
+
+	book := func(id string) Burnable {
+		return &trackedBurnable{
+			Burnable: NewBook(&BookParams{BurnDuration: time.Hour, Clock: clock, ID: id}),
+			burning:  &burning,
+			peak:     &peak,
+		}
+	}
+
+	waitFor := func(description string, condition func() bool) {
+		timeoutCh := time.After(suite.waitDuration)
+
+		for !condition() {
+			select {
+			case <-time.After(suite.burnDuration):
+			case <-timeoutCh:
+				t.Fatalf("Should have %s", description)
+			}
+		}
+	}
+
+	/// When
+	group.Consume(provider)
+	sourceCh <- []Burnable{book("a"), book("b"), book("c"), book("d")}
+
+	if err := group.Add(thief); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor("stolen 2 books", func() bool { return thief.Load().Burning == 2 })
+
+	// The slow incinerator is still busy with its batch, so this one goes to the
+	// thief, which must queue it behind the books it has stolen.
+	sourceCh <- []Burnable{book("e"), book("f")}
+	waitFor("queued the next batch", func() bool { return thief.Load().Queued == 2 })
+	time.Sleep(suite.burnDuration * 10)
+	load := thief.Load()
+
+	timeoutCh := time.After(suite.waitDuration)
+
+	for burned := 0; burned < 6; {
+		select {
+		case <-group.BurnResultChannel():
+			burned++
+
+		case <-time.After(suite.burnDuration * 10):
+			clock.Advance(time.Hour)
+
+		case <-timeoutCh:
+			t.Fatalf("Should have burned 6 books, got %d", burned)
+		}
+	}
+
+	/// Then
+	if load.Burning != 2 || load.Queued != 2 {
+		t.Errorf("Should have burned 2 and queued 2 on the thief, got %v", load)
+	}
+
+	if peak := atomic.LoadInt64(&peak); peak > 3 {
+		t.Errorf("Should have burned at most 3 at once across both, got %d", peak)
+	}
+
+	expected := map[string]int{"a": 1, "b": 1, "c": 1, "d": 1, "e": 1, "f": 1}
+
+	if burned := group.BurnedIDMap(); !reflect.DeepEqual(burned, expected) {
+		t.Errorf("Should have burned %v exactly once, got %v", expected, burned)
+	}
+}